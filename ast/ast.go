@@ -2,8 +2,6 @@ package ast
 
 import (
 	source "go/token"
-
-	"github.com/tifye/flamingo/assert"
 )
 
 type NodeType string
@@ -12,6 +10,20 @@ const (
 	NodeTypeFrag NodeType = "fragment"
 )
 
+// Directive kinds understood by renderers
+const (
+	DirectiveOn   = "on"
+	DirectiveBind = "bind"
+)
+
+const (
+	// SlotTag is the tag name of elements parsed as a Slot
+	SlotTag = "slot"
+	// SlotAttribute is the attribute of an element passed to a
+	// component naming the slot the element is rendered in
+	SlotAttribute = "slot"
+)
+
 type Node interface {
 	Pos() source.Pos
 	End() source.Pos
@@ -32,6 +44,7 @@ type (
 	CodeBlock struct {
 		TopFence    source.Pos
 		BottomFence source.Pos
+		CodePos     source.Pos // position of the first character of Code
 		Code        string
 	}
 
@@ -44,6 +57,7 @@ type (
 		RightChevron source.Pos // right chevron of close tag
 		Name         *Ident
 		Attrs        []*Attribute
+		Directives   []*Directive
 		Nodes        []RenderNode
 	}
 
@@ -52,15 +66,77 @@ type (
 		Name     string
 	}
 
+	// An Attribute node represents a name/value pair on an Element.
+	// The value is either a string literal or, when ValueExpr is
+	// set, a Go expression evaluated at render time.
 	Attribute struct {
 		Name         *Ident
 		ValueLiteral string
+		ValueExpr    *Expr
+	}
+
+	// A Directive node represents a namespaced attribute, such as
+	// on:click={c.handleClick}, whose Go value is handed to the
+	// renderer rather than set as a plain attribute.
+	Directive struct {
+		Kind    *Ident // namespace before the colon, e.g. on or bind
+		Name    *Ident // name after the colon, e.g. click or value
+		Handler *Expr
 	}
 
 	Text struct {
 		Position source.Pos
 		Literal  string
 	}
+
+	// An IfBlock node represents conditionally rendered markup,
+	// {#if Cond}...{:else}...{/if}. An {:else if} branch is
+	// represented as an IfBlock in Else, sharing the Close of
+	// the block it continues.
+	IfBlock struct {
+		Open  source.Pos // position of "{#" or, for else if, "{:"
+		Cond  *Expr
+		Then  *Fragment
+		Else  RenderNode // *IfBlock, *Fragment or nil
+		Close source.Pos // position of "}" closing {/if}
+	}
+
+	// An EachBlock node represents markup repeated for every
+	// element of a collection, {#each Expr as Value, Index (Key)}
+	// ...{:else}...{/each}, with Else rendered when there are none.
+	EachBlock struct {
+		Open  source.Pos // position of "{#"
+		Expr  *Expr      // ranged over expression
+		Value *Ident     // value variable or nil
+		Index *Ident     // index variable or nil
+		Key   *Expr      // key expression or nil
+		Body  *Fragment
+		Else  *Fragment  // else branch or nil
+		Close source.Pos // position of "}" closing {/each}
+	}
+
+	// A Slot node represents the place where a component renders
+	// the markup passed to it, <slot name="footer">...</slot>, with
+	// its children rendered as fallback when none is passed.
+	Slot struct {
+		LeftChevron  source.Pos // left chevron of opening tag
+		RightChevron source.Pos // right chevron of close tag
+		Name         string     // name of the slot, empty for the default slot
+		Fallback     *Fragment
+	}
+
+	// A BadNode is a placeholder for markup containing syntax
+	// errors for which no correct node could be created.
+	BadNode struct {
+		From, To source.Pos // position range of the bad markup
+	}
+
+	// An Expr node represents a Go expression enclosed in braces
+	Expr struct {
+		Lbrace source.Pos // position of "{"
+		Code   string     // Go source of the expression, without braces
+		Rbrace source.Pos // position of "}"
+	}
 )
 
 func (n *File) Pos() source.Pos {
@@ -70,17 +146,25 @@ func (n *File) Pos() source.Pos {
 	if n.Fragment != nil {
 		return n.Fragment.Pos()
 	}
-	panic("node has no source location")
+	return source.NoPos
 }
 func (n *CodeBlock) Pos() source.Pos { return n.TopFence }
 func (n *Fragment) Pos() source.Pos {
-	assert.Assert(len(n.Nodes) > 0, "fragment has no children")
+	if len(n.Nodes) == 0 {
+		return source.NoPos
+	}
 	return n.Nodes[0].Pos()
 }
 func (n *Element) Pos() source.Pos   { return n.LeftChevron }
 func (n *Ident) Pos() source.Pos     { return n.Position }
 func (n *Attribute) Pos() source.Pos { return n.Name.Pos() }
+func (n *Directive) Pos() source.Pos { return n.Kind.Pos() }
 func (n *Text) Pos() source.Pos      { return n.Position }
+func (n *Expr) Pos() source.Pos      { return n.Lbrace }
+func (n *IfBlock) Pos() source.Pos   { return n.Open }
+func (n *EachBlock) Pos() source.Pos { return n.Open }
+func (n *Slot) Pos() source.Pos      { return n.LeftChevron }
+func (n *BadNode) Pos() source.Pos   { return n.From }
 
 func (n *File) End() source.Pos {
 	if n.Fragment != nil {
@@ -89,25 +173,46 @@ func (n *File) End() source.Pos {
 	if n.CodeBlock != nil {
 		return n.CodeBlock.BottomFence
 	}
-	panic("node has no source location")
+	return source.NoPos
 }
 func (n *CodeBlock) End() source.Pos { return n.BottomFence }
 func (n *Fragment) End() source.Pos {
 	l := len(n.Nodes)
-	assert.Assert(l > 0, "fragment has no children")
+	if l == 0 {
+		return source.NoPos
+	}
 	return n.Nodes[l-1].End()
 }
 func (n *Element) End() source.Pos { return n.RightChevron }
 func (n *Ident) End() source.Pos   { return source.Pos(int(n.Position) + len(n.Name)) }
 func (n *Attribute) End() source.Pos {
+	if n.ValueExpr != nil {
+		return n.ValueExpr.End()
+	}
 	if l := len(n.ValueLiteral); l > 0 {
 		return source.Pos(int(n.Name.Pos()) + l + 3) // +3 for =, ", "
 	}
 	return n.Name.End()
 }
-func (n *Text) End() source.Pos { return source.Pos(int(n.Position) + len(n.Literal)) }
+func (n *Directive) End() source.Pos {
+	if n.Handler != nil {
+		return n.Handler.End()
+	}
+	return n.Name.End()
+}
+func (n *Text) End() source.Pos      { return source.Pos(int(n.Position) + len(n.Literal)) }
+func (n *Expr) End() source.Pos      { return n.Rbrace + 1 }
+func (n *IfBlock) End() source.Pos   { return n.Close + 1 }
+func (n *EachBlock) End() source.Pos { return n.Close + 1 }
+func (n *Slot) End() source.Pos      { return n.RightChevron }
+func (n *BadNode) End() source.Pos   { return n.To }
 
 // elementNode() makes sure that only element nodes can be assigned to an Element
-func (*Element) elementNode()  {}
-func (*Text) elementNode()     {}
-func (*Fragment) elementNode() {}
+func (*Element) elementNode()   {}
+func (*Text) elementNode()      {}
+func (*Expr) elementNode()      {}
+func (*Fragment) elementNode()  {}
+func (*IfBlock) elementNode()   {}
+func (*EachBlock) elementNode() {}
+func (*Slot) elementNode()      {}
+func (*BadNode) elementNode()   {}
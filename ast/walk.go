@@ -26,12 +26,51 @@ func Walk(v Visitor, node Node) {
 	case *Element:
 		Walk(v, n.Name)
 		walkList(v, n.Attrs)
+		walkList(v, n.Directives)
 		walkList(v, n.Nodes)
 	case *Attribute:
 		Walk(v, n.Name)
+		if n.ValueExpr != nil {
+			Walk(v, n.ValueExpr)
+		}
+	case *Directive:
+		Walk(v, n.Kind)
+		Walk(v, n.Name)
+		if n.Handler != nil {
+			Walk(v, n.Handler)
+		}
+	case *IfBlock:
+		if n.Cond != nil {
+			Walk(v, n.Cond)
+		}
+		Walk(v, n.Then)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *EachBlock:
+		if n.Expr != nil {
+			Walk(v, n.Expr)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+		if n.Key != nil {
+			Walk(v, n.Key)
+		}
+		Walk(v, n.Body)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+	case *Slot:
+		Walk(v, n.Fallback)
 	case *Text:
+	case *Expr:
 	case *Ident:
 	case *CodeBlock:
+	case *BadNode:
 	default:
 		panic("cannot walk node of type: " + reflect.TypeOf(n).String())
 	}
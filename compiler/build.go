@@ -0,0 +1,343 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"go/importer"
+	source "go/token"
+	"go/types"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tifye/flamingo/assert"
+	"github.com/tifye/flamingo/ast"
+	"github.com/tifye/flamingo/parser"
+)
+
+// A Dir is a directory of components compiled by Build.
+type Dir struct {
+	// Path names the directory in the positions of errors and
+	// line directives, and is the directory the packages imported
+	// by its package are resolved from. If empty, the working
+	// directory is used and the files are named as in FS.
+	Path string
+	// Pkg is the name of the package. If empty, it is that of
+	// the Go files of the directory or, without any, the one
+	// declared by the code blocks of its templates, or main if
+	// none is. Templates declaring another package are errors.
+	Pkg  string
+	FS   fs.FS
+	Sink Sink
+}
+
+// Build compiles the components of each of dirs as CompileDir does,
+// parsing, compiling and type-checking their files on at most
+// workers goroutines, or runtime.GOMAXPROCS(0) if workers is not
+// positive. The templates of all are parsed into fset. The Sink of
+// a directory is used by one goroutine at a time.
+//
+// A directory is written once all its templates compile and
+// type-check, regardless of the errors of other directories. The
+// errors of all are returned as a parser.ErrorList sorted by
+// position. Once ctx is done no more files are compiled or written,
+// and the error of ctx is returned.
+//
+// The packages imported by those of dirs are type-checked from
+// source once and shared by all the Builds of the process, so
+// changes made to them after are not seen by later Builds.
+func Build(ctx context.Context, fset *source.FileSet, dirs []Dir, workers int) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	b := &builder{
+		fset:     fset,
+		pool:     pool{sem: make(chan struct{}, workers)},
+		importer: sharedImporter(),
+	}
+
+	// Each directory is driven by its own goroutine, which
+	// waits on the tasks it runs on the pool
+	dirErrs := make([]parser.ErrorList, len(dirs))
+	var wg sync.WaitGroup
+	for i, d := range dirs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dirErrs[i] = b.compileDir(ctx, d)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var errs parser.ErrorList
+	for _, list := range dirErrs {
+		errs = append(errs, list...)
+	}
+	errs.Sort()
+	return errs.Err()
+}
+
+type builder struct {
+	fset     *source.FileSet
+	pool     pool
+	importer *lockedImporter
+}
+
+// compiledFile is a template of a directory being compiled.
+type compiledFile struct {
+	name string // of the component
+	root *ast.File
+	comp *component
+	out  []byte
+	errs parser.ErrorList
+}
+
+// compileDir compiles and writes the components of d, returning
+// the errors found. It returns early once ctx is done.
+func (b *builder) compileDir(ctx context.Context, d Dir) parser.ErrorList {
+	assert.AssertNotNil(d.Sink)
+
+	var errs parser.ErrorList
+	entries, err := fs.ReadDir(d.FS, ".")
+	if err != nil {
+		addError(&errs, d.Path, fmt.Errorf("read dir: %s", err))
+		return errs
+	}
+
+	files := make([]*compiledFile, 0, len(entries))
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".flamingo"); ok && !entry.IsDir() {
+			files = append(files, &compiledFile{name: name})
+		}
+	}
+
+	// All files are parsed before any is compiled so that
+	// components can use the others of the package
+	b.pool.each(ctx, len(files), func(i int) {
+		b.parseFile(d, files[i])
+	})
+	if ctx.Err() != nil {
+		return errs
+	}
+
+	pkg, err := packageName(d, files)
+	if err != nil {
+		addError(&errs, d.Path, err)
+		return errs
+	}
+	b.pool.each(ctx, len(files), func(i int) {
+		if files[i].root != nil {
+			b.analyzeFile(d, pkg, files[i])
+		}
+	})
+	comps := make(map[string]*component, len(files))
+	for _, f := range files {
+		errs = append(errs, f.errs...)
+		comps[f.name] = f.comp
+	}
+	if len(errs) > 0 || ctx.Err() != nil {
+		return errs
+	}
+
+	b.pool.each(ctx, len(files), func(i int) {
+		f := files[i]
+		out := &bytes.Buffer{}
+		if err := compileFile(pkg, b.fset, f.name, f.root, comps, out); err != nil {
+			addError(&f.errs, filepath.Join(d.Path, f.name+".flamingo"), err)
+			return
+		}
+		f.out = out.Bytes()
+	})
+	generated := make(map[string][]byte, len(files))
+	for _, f := range files {
+		errs = append(errs, f.errs...)
+		generated[f.name+"_flamingo.go"] = f.out
+	}
+	if len(errs) > 0 || ctx.Err() != nil {
+		return errs
+	}
+
+	// The generated code is type-checked before any of it
+	// is written, reporting mistakes at the templates
+	b.pool.each(ctx, 1, func(int) {
+		err = checkPackage(pkg, d.Path, d.FS, b.importer, generated)
+	})
+	if err != nil {
+		addError(&errs, d.Path, err)
+		return errs
+	}
+	if ctx.Err() != nil {
+		return errs
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(generated)) {
+		if err := d.Sink.WriteFile(name, generated[name]); err != nil {
+			addError(&errs, filepath.Join(d.Path, name), err)
+		}
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), "_flamingo.go")
+		if !ok || entry.IsDir() || comps[name] != nil || !isGenerated(d.FS, entry.Name()) {
+			continue
+		}
+		if err := d.Sink.Remove(entry.Name()); err != nil {
+			addError(&errs, filepath.Join(d.Path, entry.Name()), err)
+		}
+	}
+	return errs
+}
+
+// parseFile parses the template of f in d.
+func (b *builder) parseFile(d Dir, f *compiledFile) {
+	filename := f.name + ".flamingo"
+	name := filepath.Join(d.Path, filename)
+
+	inputb, err := fs.ReadFile(d.FS, filename)
+	if err != nil {
+		addError(&f.errs, name, err)
+		return
+	}
+
+	root, err := parser.ParseFile(b.fset, name, inputb)
+	if err != nil {
+		addError(&f.errs, name, err)
+		return
+	}
+	f.root = root
+}
+
+// analyzeFile analyzes the component of f, parsed
+// from d, as a component of the package pkg.
+func (b *builder) analyzeFile(d Dir, pkg string, f *compiledFile) {
+	name := filepath.Join(d.Path, f.name+".flamingo")
+
+	code, err := parseCodeBlock(pkg, f.root.CodeBlock)
+	if err != nil {
+		addError(&f.errs, name, err)
+		return
+	}
+	if code.pkg != pkg {
+		f.errs.Add(b.fset.Position(code.templatePos(code.file.Name.Pos())), fmt.Sprintf("package %s; expected package %s", code.pkg, pkg))
+		return
+	}
+	comp, err := analyzeComponent(f.name, code)
+	if err != nil {
+		addError(&f.errs, name, err)
+		return
+	}
+	f.comp = comp
+}
+
+// packageName returns the name of the package of d, as
+// documented by Dir.Pkg, the templates of d being files.
+func packageName(d Dir, files []*compiledFile) (string, error) {
+	if d.Pkg != "" {
+		return d.Pkg, nil
+	}
+
+	ctxt := buildContext(d.FS)
+	bpkg, err := ctxt.ImportDir(".", 0)
+	var noGo *build.NoGoError
+	switch {
+	case errors.As(err, &noGo):
+	case err != nil:
+		return "", err
+	default:
+		return bpkg.Name, nil
+	}
+
+	for _, f := range files {
+		if f.root == nil {
+			continue
+		}
+		if pkg := declaredPackage(f.root.CodeBlock); pkg != "" {
+			return pkg, nil
+		}
+	}
+	return "main", nil
+}
+
+// pool runs tasks on a bounded number of goroutines.
+type pool struct {
+	sem chan struct{}
+}
+
+// each runs fn for each of 0 to n-1 on the pool, returning
+// once all have run. No more are run once ctx is done.
+func (p *pool) each(ctx context.Context, n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i := range n {
+		select {
+		case p.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		if ctx.Err() != nil {
+			<-p.sem
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-p.sem
+				wg.Done()
+			}()
+			fn(i)
+		}()
+	}
+}
+
+// sharedImporter returns the importer shared by all Builds, as
+// importing the packages from source takes most of the time of
+// type-checking the generated code.
+var sharedImporter = sync.OnceValue(func() *lockedImporter {
+	return &lockedImporter{
+		imp:  importer.ForCompiler(source.NewFileSet(), "source", nil).(types.ImporterFrom),
+		pkgs: make(map[importKey]*types.Package),
+	}
+})
+
+// lockedImporter imports packages for packages type-checked
+// concurrently, one at a time, sharing the imported packages.
+// These are kept by the path they are imported by and the
+// directory it is resolved from, skipping the resolution
+// done by imp for the packages imported before.
+type lockedImporter struct {
+	mu   sync.Mutex
+	imp  types.ImporterFrom
+	pkgs map[importKey]*types.Package
+}
+
+type importKey struct {
+	path, dir string
+}
+
+func (l *lockedImporter) Import(path string) (*types.Package, error) {
+	return l.ImportFrom(path, ".", 0)
+}
+
+func (l *lockedImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := importKey{path, dir}
+	if pkg := l.pkgs[key]; pkg != nil {
+		return pkg, nil
+	}
+	pkg, err := l.imp.ImportFrom(path, dir, mode)
+	if err == nil {
+		l.pkgs[key] = pkg
+	}
+	return pkg, err
+}
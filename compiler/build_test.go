@@ -0,0 +1,130 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	source "go/token"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/parser"
+)
+
+func TestBuild(t *testing.T) {
+	dirs := func() ([]Dir, []*memSink) {
+		sinks := []*memSink{{}, {}, {}}
+		return []Dir{
+			{Path: "testdata/check/valid", Pkg: "main", FS: os.DirFS("testdata/check/valid"), Sink: sinks[0]},
+			{Path: "testdata/check/invalid", Pkg: "main", FS: os.DirFS("testdata/check/invalid"), Sink: sinks[1]},
+			{Path: "meep", Pkg: "meep", FS: fstest.MapFS{
+				"Bad.flamingo":  {Data: []byte("<div></span>")},
+				"Meep.flamingo": {Data: []byte("<p>{c.meep}</p>")},
+			}, Sink: sinks[2]},
+		}, sinks
+	}
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			dirs, sinks := dirs()
+			err := Build(context.Background(), source.NewFileSet(), dirs, workers)
+			var errs parser.ErrorList
+			require.ErrorAs(t, err, &errs)
+
+			msgs := make([]string, len(errs))
+			for i, e := range errs {
+				msgs[i] = e.Error()
+			}
+			assert.Equal(t, []string{
+				"meep/Bad.flamingo:1:2: unclosed element div",
+				"meep/Bad.flamingo:1:8: unexpected closing tag span, expected div",
+				"testdata/check/invalid/Counter.flamingo:7:4: c.cuont undefined (type *Counter has no field or method cuont)",
+				"testdata/check/invalid/Counter.flamingo:10:35: c.Count undefined (type *Counter has no field or method Count, but does have field count)",
+			}, msgs, "expected the errors of all directories ordered by position")
+
+			assert.Len(t, sinks[0].files, 2, "expected directories without errors to be written")
+			assert.Empty(t, sinks[1].files)
+			assert.Empty(t, sinks[2].files)
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		dirs, sinks := dirs()
+		err := Build(ctx, source.NewFileSet(), dirs, 0)
+		assert.ErrorIs(t, err, context.Canceled)
+		for _, sink := range sinks {
+			assert.Empty(t, sink.files, "expected nothing to be written")
+		}
+	})
+}
+
+func BenchmarkBuild(b *testing.B) {
+	const numDirs, numComps = 8, 16
+
+	dirs := make([]Dir, numDirs)
+	for i := range dirs {
+		fsys := fstest.MapFS{}
+		var app strings.Builder
+		app.WriteString("<main>\n")
+		for j := range numComps {
+			fsys[fmt.Sprintf("Item%d.flamingo", j)] = &fstest.MapFile{Data: fmt.Appendf(nil, benchComponent, j)}
+			fmt.Fprintf(&app, "\t<Item%d label=\"item %d\"/>\n", j, j)
+		}
+		app.WriteString("</main>")
+		fsys["App.flamingo"] = &fstest.MapFile{Data: []byte(app.String())}
+		dirs[i] = Dir{Pkg: "main", FS: fsys, Sink: discardSink{}}
+	}
+
+	// The imported packages, shared by all Builds, are
+	// type-checked once before the builds measured
+	if err := Build(context.Background(), source.NewFileSet(), dirs, 0); err != nil {
+		b.Fatal(err)
+	}
+	for _, bc := range []struct {
+		name    string
+		workers int
+	}{
+		{"sequential", 1},
+		{"parallel", 0},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for b.Loop() {
+				err := Build(context.Background(), source.NewFileSet(), dirs, bc.workers)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+const benchComponent = `---
+import "strings"
+
+type Item%d struct {
+	items []string
+	Label string ` + "`prop:\"\"`" + `
+}
+
+func (c *Item%[1]d) add() {
+	c.items = append(c.items, c.Label)
+	c.Invalidate()
+}
+---
+<ul class="list" on:click={c.add}>
+	{#each c.items as item}
+		<li>{strings.ToUpper(item)}</li>
+	{/each}
+	{#if len(c.items) == 0}<p>{c.Label}</p>{/if}
+</ul>`
+
+type discardSink struct{}
+
+func (discardSink) WriteFile(string, []byte) error { return nil }
+func (discardSink) Remove(string) error            { return nil }
@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/build"
+	goparser "go/parser"
+	source "go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tifye/flamingo/parser"
+)
+
+// checkPackage type-checks the package pkg in the root of fsys,
+// named dir, made up of the generated files, keyed by file name, and
+// its other Go files, importing the packages it imports with imp.
+//
+// It returns the errors found in the code generated from the
+// templates, positioned in these by the line directives of the
+// generated code, and the package clauses of Go files declaring
+// another package than pkg, which would otherwise surface as errors
+// of the generated code. Other errors are left to the Go toolchain
+// to report, as is the package if one of its Go files fails to parse.
+func checkPackage(pkg string, dir string, fsys fs.FS, imp types.Importer, generated map[string][]byte) error {
+	if len(generated) == 0 {
+		return nil
+	}
+
+	fset := source.NewFileSet()
+	files := make([]*goast.File, 0, len(generated))
+	templates := make(map[string]bool, len(generated))
+	for _, name := range slices.Sorted(maps.Keys(generated)) {
+		file, err := goparser.ParseFile(fset, filepath.Join(dir, name), generated[name], goparser.SkipObjectResolution)
+		if err != nil {
+			return fmt.Errorf("generated invalid Go: %w", err)
+		}
+		files = append(files, file)
+		templates[filepath.Join(dir, strings.TrimSuffix(name, "_flamingo.go")+".flamingo")] = true
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("read dir: %s", err)
+	}
+	ctxt := buildContext(fsys)
+	for _, entry := range entries {
+		name := entry.Name()
+		// The generated files, including stale ones, are
+		// replaced by those of the templates being compiled
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || generated[name] != nil || isGenerated(fsys, name) {
+			continue
+		}
+		if match, err := ctxt.MatchFile(".", name); err != nil || !match {
+			continue
+		}
+
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil
+		}
+		file, err := goparser.ParseFile(fset, filepath.Join(dir, name), src, goparser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+		files = append(files, file)
+	}
+
+	var errs parser.ErrorList
+	for _, file := range files {
+		if file.Name.Name != pkg {
+			errs.Add(fset.Position(file.Name.Pos()), fmt.Sprintf("package %s; expected package %s", file.Name.Name, pkg))
+		}
+	}
+	if len(errs) > 0 {
+		return errs.Err()
+	}
+
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			terr, ok := err.(types.Error)
+			if !ok {
+				return
+			}
+			if pos := terr.Fset.Position(terr.Pos); templates[pos.Filename] {
+				errs.Add(pos, terr.Msg)
+			}
+		},
+	}
+	// The errors are those reported to conf.Error
+	_, _ = conf.Check(pkg, fset, files, nil)
+
+	errs.RemoveMultiples()
+	return errs.Err()
+}
+
+// buildContext returns the default build context reading the
+// directories of fsys. Generated files are left out of the
+// directories read as they are replaced by the templates.
+func buildContext(fsys fs.FS) *build.Context {
+	ctxt := build.Default
+	ctxt.JoinPath = path.Join
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}
+	ctxt.IsDir = func(name string) bool {
+		info, err := fs.Stat(fsys, name)
+		return err == nil && info.IsDir()
+	}
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]fs.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			if strings.HasSuffix(entry.Name(), "_flamingo.go") && isGenerated(fsys, path.Join(dir, entry.Name())) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	}
+	return &ctxt
+}
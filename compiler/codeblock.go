@@ -0,0 +1,279 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	gotoken "go/token"
+	pathpkg "path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tifye/flamingo/ast"
+)
+
+// runtimeImports are the packages referenced by generated code
+// under their default package names.
+var runtimeImports = [...]string{
+	"github.com/tifye/flamingo/render",
+}
+
+type importSpec struct {
+	Name string // explicit package name, empty if none
+	Path string
+}
+
+func (s importSpec) String() string {
+	if s.Name != "" {
+		return fmt.Sprintf("%s %q", s.Name, s.Path)
+	}
+	return strconv.Quote(s.Path)
+}
+
+// std reports whether the package is in the standard library,
+// whose import paths have no dot in their first element.
+func (s importSpec) std() bool {
+	first, _, _ := strings.Cut(s.Path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// name returns the name the package is imported under. Without an
+// explicit name, the package name is assumed from the import path
+// as goimports does, its last element without any "go-" prefix or
+// anything following the first character not valid in a name. A
+// last element which is a major version such as "v2" is skipped.
+func (s importSpec) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	base := pathpkg.Base(s.Path)
+	if v, ok := strings.CutPrefix(base, "v"); ok {
+		if _, err := strconv.Atoi(v); err == nil {
+			if dir := pathpkg.Dir(s.Path); dir != "." {
+				base = pathpkg.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// codeBlock is the parsed Go source of a component's code fence.
+type codeBlock struct {
+	fset    *gotoken.FileSet
+	file    *goast.File
+	src     string
+	pkg     string
+	imports []importSpec
+	// declsOffset is the offset in src of the source following
+	// the package clause and imports, holding the declarations.
+	declsOffset int
+	// start is the position in the template of the code
+	// following prefix, the package clause prepended to
+	// code blocks without one.
+	start  gotoken.Pos
+	prefix int
+}
+
+// parseCodeBlock parses the Go code inside block. Code blocks
+// may omit the package clause in which case pkg is used.
+// A nil block is treated as an empty code block.
+func parseCodeBlock(pkg string, block *ast.CodeBlock) (*codeBlock, error) {
+	src := ""
+	if block != nil {
+		src = block.Code
+	}
+	prefix := ""
+	if !hasPackageClause(src) {
+		// Prepended on the same line so line numbers
+		// are preserved for diagnostics.
+		prefix = fmt.Sprintf("package %s;", pkg)
+		src = prefix + src
+	}
+
+	fset := gotoken.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", src, goparser.ParseComments|goparser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parse code block: %w", err)
+	}
+
+	cb := &codeBlock{
+		fset:   fset,
+		file:   file,
+		src:    src,
+		pkg:    file.Name.Name,
+		prefix: len(prefix),
+	}
+	if block != nil {
+		cb.start = block.CodePos
+	}
+
+	declStart := file.Name.End()
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*goast.GenDecl)
+		if !ok || gen.Tok != gotoken.IMPORT {
+			break
+		}
+		declStart = gen.End()
+	}
+	cb.declsOffset = max(fset.Position(declStart).Offset, len(prefix))
+
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid import path %s", spec.Path.Value)
+		}
+		imp := importSpec{Path: path}
+		if spec.Name != nil {
+			imp.Name = spec.Name.Name
+		}
+		cb.imports = append(cb.imports, imp)
+	}
+
+	return cb, nil
+}
+
+// hasPackageClause reports whether the first token in src,
+// ignoring comments, is the package keyword.
+func hasPackageClause(src string) bool {
+	var s scanner.Scanner
+	fset := gotoken.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, []byte(src), nil, 0)
+	_, tok, _ := s.Scan()
+	return tok == gotoken.PACKAGE
+}
+
+// declaredPackage returns the name of the package declared
+// by the code of block, or "" if it declares none.
+func declaredPackage(block *ast.CodeBlock) string {
+	if block == nil || !hasPackageClause(block.Code) {
+		return ""
+	}
+	file, err := goparser.ParseFile(gotoken.NewFileSet(), "", block.Code, goparser.PackageClauseOnly)
+	if err != nil {
+		return ""
+	}
+	return file.Name.Name
+}
+
+// mergeImports returns the code block's imports of the packages
+// named in refs, followed by the runtime imports named in refs
+// which it does not already provide. Blank and dot imports are
+// always kept. Importing another package under the name of a
+// runtime import is an error as generated code would no longer
+// refer to the runtime package.
+func (cb *codeBlock) mergeImports(refs map[string]bool) ([]importSpec, error) {
+	imports := make([]importSpec, 0, len(cb.imports))
+	for _, imp := range cb.imports {
+		if name := imp.name(); name == "_" || name == "." || refs[name] {
+			imports = append(imports, imp)
+		}
+	}
+
+outer:
+	for _, path := range runtimeImports {
+		name := pathpkg.Base(path)
+		if !refs[name] {
+			continue
+		}
+		for _, imp := range cb.imports {
+			if imp.Path == path && imp.name() == name {
+				continue outer
+			}
+			if imp.Path != path && imp.name() == name {
+				return nil, fmt.Errorf("import %s clashes with generated import %q", imp, path)
+			}
+		}
+		imports = append(imports, importSpec{Path: path})
+	}
+
+	return imports, nil
+}
+
+// packageRefs returns the names of the packages file refers to,
+// which are the identifiers selected from that are not declared
+// in file.
+func packageRefs(file *goast.File) map[string]bool {
+	refs := make(map[string]bool)
+	goast.Inspect(file, func(n goast.Node) bool {
+		if sel, ok := n.(*goast.SelectorExpr); ok {
+			if id, ok := sel.X.(*goast.Ident); ok && id.Obj == nil {
+				refs[id.Name] = true
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+// templatePos returns the position in the template of pos in
+// the code block, or gotoken.NoPos if it is unknown.
+func (cb *codeBlock) templatePos(pos gotoken.Pos) gotoken.Pos {
+	if !cb.start.IsValid() || !pos.IsValid() {
+		return gotoken.NoPos
+	}
+	return cb.start + gotoken.Pos(cb.fset.Position(pos).Offset-cb.prefix)
+}
+
+// writeDecls writes the declarations of the code block to out, each
+// preceded by the line directive mapping it to the template. If field
+// is not empty, it is inserted as the first field of the struct type
+// whose fields are opened by the brace at lbrace.
+func (cb *codeBlock) writeDecls(out *bytes.Buffer, lines *lineDirectives, lbrace gotoken.Pos, field string) {
+	tokFile := cb.fset.File(cb.file.Pos())
+	at := cb.declsOffset
+	copyTo := func(end int) {
+		out.WriteString(cb.src[at:end])
+		at = end
+	}
+	// directive returns the directive of the code from at,
+	// which starts a line in the output, skipping leading blanks
+	directive := func() string {
+		blanks := len(cb.src[at:]) - len(strings.TrimLeft(cb.src[at:], " \t"))
+		return lines.markCopied(cb.templatePos(tokFile.Pos(at + blanks)))
+	}
+
+	embedAt := -1
+	if field != "" {
+		embedAt = cb.fset.Position(lbrace).Offset + 1
+	}
+
+	for _, decl := range cb.file.Decls {
+		start := cb.fset.Position(decl.Pos()).Offset
+		if start < at {
+			// Imports, which are written by the compiler
+			continue
+		}
+		copyTo(start)
+		if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			out.WriteString("\n")
+		}
+		out.WriteString(directive())
+
+		if end := cb.fset.Position(decl.End()).Offset; start < embedAt && embedAt <= end {
+			copyTo(embedAt)
+			fmt.Fprintf(out, "\n\t%s\n", field)
+			// The lines following the inserted field are mapped
+			// anew, the directive taking the place of the line
+			// break following the brace
+			rest, newline := strings.CutPrefix(cb.src[at:], "\n")
+			at += len(cb.src[at:]) - len(rest)
+			if d := directive(); d != "" {
+				out.WriteString(d)
+			} else if newline {
+				at--
+			}
+		}
+	}
+	copyTo(len(cb.src))
+}
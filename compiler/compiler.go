@@ -1,130 +1,295 @@
 package compiler
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"go/format"
+	goparser "go/parser"
+	"go/scanner"
 	source "go/token"
 	"io"
 	"io/fs"
-	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"sync/atomic"
+	"unicode"
 
 	"github.com/tifye/flamingo/assert"
 	"github.com/tifye/flamingo/ast"
-	"github.com/tifye/flamingo/lexer"
 	"github.com/tifye/flamingo/parser"
 )
 
-func CompileDir(pkg string, fset *source.FileSet, path string, output func(fs.FileInfo) (io.WriteCloser, error)) error {
-	assert.AssertNotNil(output)
+// generatedHeader starts the code generated for a template,
+// marking it as generated for tools and for CompileDir.
+const generatedHeader = "// Code generated by flamingo. DO NOT EDIT.\n"
 
-	entries, err := os.ReadDir(path)
+// CompileDir compiles the components of the package pkg, named as
+// described by Dir.Pkg if empty, whose templates are the .flamingo
+// files in the root of fsys, writing
+// the code generated for Name.flamingo to sink as Name_flamingo.go.
+// The generated files of templates which no longer exist are
+// removed from sink.
+//
+// The generated code is type-checked with the other Go files of
+// the package in fsys, the packages it imports being resolved from
+// the working directory. Nothing is written unless all templates
+// compile and type-check, the errors of all being returned as a
+// parser.ErrorList positioned in the templates where known.
+//
+// See Build for compiling several directories concurrently.
+func CompileDir(pkg string, fset *source.FileSet, fsys fs.FS, sink Sink) error {
+	return Build(context.Background(), fset, []Dir{{Pkg: pkg, FS: fsys, Sink: sink}}, 0)
+}
+
+// addError adds err, found compiling the file name, to errs,
+// keeping the positions of the errors of a parser.ErrorList.
+func addError(errs *parser.ErrorList, name string, err error) {
+	var list parser.ErrorList
+	if errors.As(err, &list) {
+		*errs = append(*errs, list...)
+		return
+	}
+	errs.Add(source.Position{Filename: name}, err.Error())
+}
+
+// isGenerated reports whether the file name in fsys
+// is code generated for a template.
+func isGenerated(fsys fs.FS, name string) bool {
+	f, err := fsys.Open(name)
 	if err != nil {
-		return fmt.Errorf("read dir: %s", err)
+		return false
 	}
+	defer f.Close()
 
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".flamingo") {
-			continue
-		}
+	header := make([]byte, len(generatedHeader))
+	_, err = io.ReadFull(f, header)
+	return err == nil && string(header) == generatedHeader
+}
 
-		finfo, err := entry.Info()
-		if err != nil {
-			return fmt.Errorf("read file info for %s: %s", entry.Name(), err)
-		}
+// CompileFile compiles the component named file. Its template
+// may use no components of the package other than itself, see
+// CompileDir for compiling the components of a package together.
+//
+// The generated code maps its lines to the template with line
+// directives, relative to the directory of the template in fset
+// which root was parsed with. No directives are written if fset is
+// nil or the template has no file name.
+func CompileFile(pkg string, fset *source.FileSet, file string, root *ast.File, output io.Writer) error {
+	return compileFile(pkg, fset, file, root, nil, output)
+}
 
-		w, err := output(finfo)
-		if err != nil {
-			return err
-		}
+// compileFile compiles the component named file, resolving the
+// components used by its template which are not qualified by a
+// package name among comps.
+func compileFile(pkg string, fset *source.FileSet, file string, root *ast.File, comps map[string]*component, output io.Writer) error {
+	code, err := parseCodeBlock(pkg, root.CodeBlock)
+	if err != nil {
+		return err
+	}
 
-		inputb, err := os.ReadFile(entry.Name())
-		if err != nil {
-			return err
-		}
+	comp, err := analyzeComponent(file, code)
+	if err != nil {
+		return err
+	}
+	if comps == nil {
+		comps = map[string]*component{comp.Name: comp}
+	}
 
-		name := strings.TrimSuffix(entry.Name(), ".flamingo")
-		file := fset.AddFile(name, fset.Base(), len(inputb))
-		l := lexer.NewLexer(file, string(inputb))
-		p := parser.NewParser(l)
-		root := p.Parse()
-		if len(p.Errors()) > 0 {
-			return fmt.Errorf("one or more parser errors: %s", p.Errors())
-		}
+	lines := &lineDirectives{fset: fset, generated: file + "_flamingo.go"}
+	body := &bytes.Buffer{}
+	w := &walker{
+		output:    body,
+		compStack: make([]string, 0),
+		indent:    1,
+		comps:     comps,
+		code:      code,
+		lines:     lines,
+		fset:      fset,
+	}
 
-		if err := CompileFile(pkg, name, root, w); err != nil {
-			_ = w.Close()
-			return err
-		}
-		_ = w.Close()
+	// The declarations are written first, as the imports
+	// are those of the packages which they refer to
+	out := &bytes.Buffer{}
+
+	if comp.Declared {
+		code.writeDecls(out, lines, comp.Lbrace, "render.Reactive")
+	} else {
+		code.writeDecls(out, lines, source.NoPos, "")
 	}
+	fmt.Fprint(out, "\n\n")
 
-	return nil
-}
+	// Code which is generated rather than copied
+	// is mapped to the start of the markup
+	generatedPos := root.Pos()
+	if root.Fragment != nil && root.Fragment.Pos().IsValid() {
+		generatedPos = root.Fragment.Pos()
+	}
+	generated := func() {
+		out.WriteString(lines.mark(generatedPos, ""))
+	}
 
-func CompileFile(pkg string, file string, root *ast.File, output io.Writer) error {
-	imports := [...]string{
-		"github.com/tifye/flamingo/render",
-		// "github.com/tifye/flamingo/web",
+	generated()
+	if !comp.Declared {
+		fmt.Fprintf(out, "type %s struct {\n\trender.Reactive\n}\n\n", comp.Name)
 	}
 
-	w := &walker{
-		output:    output,
-		renders:   make([]string, 0),
-		compStack: make([]string, 0),
+	fmt.Fprintf(out, "type %s struct {\n", comp.PropsName())
+	for _, p := range comp.Props {
+		out.WriteString(lines.mark(code.templatePos(p.TypePos), p.Type))
+		fmt.Fprintf(out, "\t%s %s\n", p.Name, p.Type)
 	}
+	fmt.Fprint(out, "}\n\n")
+
+	generated()
+	fmt.Fprintf(out, "func %s(renderer render.Renderer, props %s) {\n", comp.FuncName(), comp.PropsName())
+	fmt.Fprintf(out, "\trenderer.Render(%s(renderer, props, nil)...)\n", comp.TreeName())
+	fmt.Fprint(out, "}\n\n")
 
-	fmt.Fprintf(output, "package %s\n\n", pkg)
-	fmt.Fprint(output, "import (\n")
-	for _, imp := range imports {
-		fmt.Fprintf(output, "\t\"%s\"\n", imp)
+	fmt.Fprintf(out, "func %s(renderer render.Renderer, props %s, slots render.Slots) []render.Component {\n", comp.TreeName(), comp.PropsName())
+	fmt.Fprintf(out, "\tc := &%s{\n", comp.Name)
+	fmt.Fprint(out, "\t\tReactive: render.NewReactive(renderer),\n")
+	for _, p := range comp.Props {
+		fmt.Fprintf(out, "\t\t%s: props.%s,\n", p.Field, p.Name)
 	}
-	fmt.Fprint(output, ")\n\n")
-	fmt.Fprintf(output, "func %s(renderer render.Renderer) {\n", file)
+	fmt.Fprint(out, "\t}\n")
 
 	walk(w, root)
+	if len(w.errs) > 0 {
+		return w.errs
+	}
+
+	if w.roots > 0 {
+		fmt.Fprint(out, "\troots := make([]render.Component, 0)\n")
+	}
+	_, _ = body.WriteTo(out)
+
+	// Attached after the tree is built so that
+	// rendering it mounts the instance
+	fmt.Fprint(out, "\n")
+	generated()
+	if w.roots > 0 {
+		fmt.Fprint(out, "\tif len(roots) > 0 {\n\t\trenderer.Attach(roots[0], c)\n\t\tc.SetRoot(roots[0])\n\t}\n")
+		fmt.Fprint(out, "\treturn roots\n")
+	} else {
+		fmt.Fprint(out, "\t_ = c\n")
+		fmt.Fprint(out, "\treturn nil\n")
+	}
+	fmt.Fprint(out, "}\n")
 
-	fmt.Fprint(output, "\n")
-	for _, r := range w.renders {
-		fmt.Fprintln(w.output, r)
+	decls := out.String()
+	genFile, err := goparser.ParseFile(source.NewFileSet(), "", "package "+code.pkg+"\n"+decls, 0)
+	if err != nil {
+		return fmt.Errorf("generated invalid Go: %w", err)
+	}
+	imports, err := code.mergeImports(packageRefs(genFile))
+	if err != nil {
+		return err
 	}
-	fmt.Fprint(output, "}")
 
-	return nil
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "%s\npackage %s\n\n", generatedHeader, code.pkg)
+	if len(imports) > 0 {
+		// Grouped as by goimports, formatting sorts the groups
+		fmt.Fprint(src, "import (\n")
+		for _, std := range []bool{true, false} {
+			for _, imp := range imports {
+				if imp.std() == std {
+					fmt.Fprintf(src, "\t%s\n", imp)
+				}
+			}
+			fmt.Fprint(src, "\n")
+		}
+		fmt.Fprint(src, ")\n\n")
+	}
+	src.WriteString(decls)
+
+	formatted, err := format.Source(src.Bytes())
+	if err == nil {
+		formatted, err = format.Source(lines.split(formatted))
+	}
+	if err != nil {
+		return fmt.Errorf("format generated code: %w", err)
+	}
+	_, err = output.Write(lines.resolve(formatted))
+	return err
 }
 
 type walker struct {
 	idCounter atomic.Int32
 	compStack []string
 	output    io.Writer
-	renders   []string
+	indent    int       // indentation of the statements written
+	opened    bool      // whether the last line written opened a block
+	roots     int       // number of components appended to roots
+	key       *ast.Expr // key of the keyed each block element visited next
+	keyBound  bool      // whether the keyed each block is bound
+	unbound   string    // element whose attributes are set once, see walkEachBlock
+	comps     map[string]*component
+	code      *codeBlock
+	lines     *lineDirectives
+	fset      *source.FileSet
+	errs      parser.ErrorList
 }
 
 func (w *walker) Visit(n ast.Node) ast.Visitor {
 	switch nt := n.(type) {
 	case *ast.Element:
-		if len(w.compStack) > 0 {
-			w.write("\n")
+		w.separate()
+		w.directive(nt.LeftChevron, "")
+		w.line("%s := renderer.NewComponent(%s)", w.curCompId(), strconv.Quote(nt.Name.Name))
+		if w.key != nil {
+			w.directive(exprPos(w.key), w.key.Code)
+			w.line("%s.SetAttribute(\"key\", %s)", w.curCompId(), strings.TrimSpace(w.key.Code))
+			if w.keyBound {
+				w.unbound = w.curCompId()
+			}
+			w.key = nil
 		}
-
-		w.write("\t%s := renderer.NewComponent(\"%s\")\n", w.curCompId(), nt.Name.Name)
-
-		if len(w.compStack) > 1 {
-			w.renders = append(w.renders, fmt.Sprintf("\trenderer.Append(%s, %s)", w.parCompId(), w.curCompId()))
-		} else {
-			w.renders = append(w.renders, fmt.Sprintf("\trenderer.Render(%s)", w.curCompId()))
-		}
-
 		return w
 	case *ast.Attribute:
 		assert.Assert(len(w.compStack) > 0, "expected to be inside a component")
-		w.write("\t%s.SetAttribute(\"%s\", \"%s\")\n", w.curCompId(), nt.Name.Name, nt.ValueLiteral)
+		if nt.ValueExpr != nil {
+			w.setAttribute(nt.Name.Name, nt.ValueExpr)
+			return w
+		}
+		w.line("%s.SetAttribute(%s, %s)", w.curCompId(), strconv.Quote(nt.Name.Name), strconv.Quote(nt.ValueLiteral))
 		return w
-	case *ast.Text:
+	case *ast.Directive:
 		assert.Assert(len(w.compStack) > 0, "expected to be inside a component")
-		w.write("\t%s.SetAttribute(\"innerText\", `%s`)\n", w.curCompId(), nt.Literal)
+		w.directive(exprPos(nt.Handler), nt.Handler.Code)
+		w.line("%s.SetAttribute(%s, %s)", w.curCompId(), strconv.Quote(nt.Kind.Name+":"+nt.Name.Name), strings.TrimSpace(nt.Handler.Code))
+		return w
+	case *ast.Text:
+		if len(w.compStack) == 0 {
+			// The space between elements at the top level
+			// has nothing to separate them in
+			if strings.TrimSpace(nt.Literal) != "" {
+				w.errorf(nt.Pos(), "text %q must be inside an element", strings.TrimSpace(nt.Literal))
+			}
+			return nil
+		}
+		w.separate()
+		w.directive(nt.Position, "")
+		w.line("%s := renderer.NewText(%s)", w.curCompId(), quoteText(nt.Literal))
+		w.appendComp()
+		return w
+	case *ast.Expr:
+		if len(w.compStack) == 0 {
+			w.errorf(nt.Pos(), "expression {%s} must be inside an element", strings.TrimSpace(nt.Code))
+			return nil
+		}
+		w.separate()
+		w.directive(nt.Lbrace, "")
+		w.line("%s := renderer.NewText(\"\")", w.curCompId())
+		w.setAttribute("text", nt)
+		w.appendComp()
 		return w
+	case *ast.BadNode:
+		w.errorf(nt.Pos(), "cannot compile malformed markup")
+		return nil
 	case *ast.Fragment, *ast.Ident, *ast.File:
 		return w
 	}
@@ -146,15 +311,595 @@ func (w *walker) write(format string, a ...any) {
 	fmt.Fprintf(w.output, format, a...)
 }
 
+// line writes a statement on its own line at the current indentation.
+func (w *walker) line(format string, a ...any) {
+	w.write("%s", strings.Repeat("\t", w.indent))
+	w.write(format+"\n", a...)
+	w.opened = strings.HasSuffix(format, "{")
+}
+
+// separate writes a blank line separating the following
+// statements from the previous ones, unless they are the
+// first statements of a block.
+func (w *walker) separate() {
+	if !w.opened {
+		w.write("\n")
+	}
+}
+
+// setAttribute writes the setting of the attribute key of the
+// current component to the Go expression value. Attributes whose
+// value depends on the component instance are bound to the fields
+// it depends on, so they are set again as these are updated, and
+// those calling functions to the signals the calls may read.
+func (w *walker) setAttribute(key string, expr *ast.Expr) {
+	value := strings.TrimSpace(expr.Code)
+	deps, bound := bindingDeps(value)
+	if !bound || w.curCompId() == w.unbound {
+		w.directive(exprPos(expr), value)
+		w.line("%s.SetAttribute(%s, %s)", w.curCompId(), strconv.Quote(key), value)
+		return
+	}
+
+	w.line("c.Bind(%s, func() {", depList(deps))
+	w.indent++
+	w.directive(exprPos(expr), value)
+	w.line("%s.SetAttribute(%s, %s)", w.curCompId(), strconv.Quote(key), value)
+	w.indent--
+	w.line("})")
+}
+
+// bindingDeps returns the fields which the output set by the Go
+// code depends on, see fieldDeps, reporting whether the output is
+// to be bound at all as it depends on the component instance or
+// calls functions, which may read signals.
+func bindingDeps(codes ...string) ([]string, bool) {
+	deps := make([]string, 0)
+	bound := false
+	for _, code := range codes {
+		fields, refers := fieldDeps(code)
+		if !refers && !hasCall(code) {
+			continue
+		}
+		bound = true
+		if fields == nil {
+			return nil, true
+		}
+		for _, f := range fields {
+			if !slices.Contains(deps, f) {
+				deps = append(deps, f)
+			}
+		}
+	}
+	return deps, bound
+}
+
+// depList returns the Go expression of the
+// fields deps passed to render.Reactive.Bind.
+func depList(deps []string) string {
+	if deps == nil {
+		return "nil"
+	}
+	quoted := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		quoted = append(quoted, strconv.Quote(dep))
+	}
+	return fmt.Sprintf("[]string{%s}", strings.Join(quoted, ", "))
+}
+
+// openBlock writes the start of the binding of a block depending
+// on the Go code of its expressions, so that it is built anew into
+// the current component, or at the top level among the roots, as
+// the fields they depend on change, and returns the function
+// writing its end, reporting whether it is bound. Blocks not
+// depending on the component instance are built once, for which
+// nothing is written.
+func (w *walker) openBlock(pos source.Pos, codes ...string) (closeBlock func(), bound bool) {
+	deps, bound := bindingDeps(codes...)
+	if !bound {
+		return func() {}, false
+	}
+
+	w.directive(pos, "")
+	end := "})"
+	if len(w.compStack) == 0 {
+		w.line("roots = append(roots, c.RootBlock(%s, func(parent render.Component) {", depList(deps))
+		w.roots++
+		end = "})...)"
+	} else {
+		w.line("c.Block(%s, %s, func(parent render.Component) {", w.curCompId(), depList(deps))
+	}
+	w.indent++
+	w.compStack = append(w.compStack, "parent")
+	return func() {
+		w.compStack = w.compStack[:len(w.compStack)-1]
+		w.indent--
+		w.line("%s", end)
+	}, true
+}
+
+// appendComp appends the current component to its
+// parent or, at the top level, to the roots.
+func (w *walker) appendComp() {
+	if len(w.compStack) > 1 {
+		w.line("renderer.Append(%s, %s)", w.parCompId(), w.curCompId())
+		return
+	}
+	w.line("roots = append(roots, %s)", w.curCompId())
+	w.roots++
+}
+
+// directive writes the line directive mapping the next line,
+// holding the code anchor generated from pos, to the template,
+// see lineDirectives.mark.
+func (w *walker) directive(pos source.Pos, anchor string) {
+	w.write("%s", w.lines.mark(pos, anchor))
+}
+
+// exprPos returns the position of the code of e,
+// ignoring any white space following its brace.
+func exprPos(e *ast.Expr) source.Pos {
+	blanks := len(e.Code) - len(strings.TrimLeftFunc(e.Code, unicode.IsSpace))
+	return e.Lbrace + 1 + source.Pos(blanks)
+}
+
+// errorf records an error found at pos while walking.
+func (w *walker) errorf(pos source.Pos, format string, a ...any) {
+	var p source.Position
+	if w.fset != nil && pos.IsValid() {
+		p = w.fset.Position(pos)
+	}
+	w.errs.Add(p, fmt.Sprintf(format, a...))
+}
+
+// quoteText returns text as a Go string literal, preferring
+// a raw string literal to keep multiline text readable.
+func quoteText(text string) string {
+	if strconv.CanBackquote(strings.ReplaceAll(text, "\n", "")) && !strings.Contains(text, "\r") {
+		return "`" + text + "`"
+	}
+	return strconv.Quote(text)
+}
+
+// goIdent returns name with all characters which
+// cannot be part of a Go identifier replaced by '_'.
+func goIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 func walkList[N ast.Node](v *walker, list []N) {
 	for _, node := range list {
 		walk(v, node)
 	}
 }
 
+// walkIfBlock writes an if statement creating the components
+// of the branch taken, bound to its conditions, see openBlock.
+func walkIfBlock(v *walker, n *ast.IfBlock) {
+	assert.AssertNotNil(n.Cond)
+
+	conds := make([]string, 0)
+	for b := n; b != nil; {
+		conds = append(conds, b.Cond.Code)
+		b, _ = b.Else.(*ast.IfBlock)
+	}
+
+	v.separate()
+	closeBlock, _ := v.openBlock(n.Pos(), conds...)
+	defer closeBlock()
+	v.directive(exprPos(n.Cond), n.Cond.Code)
+	v.line("if %s {", strings.TrimSpace(n.Cond.Code))
+	for {
+		v.indent++
+		walk(v, n.Then)
+		v.indent--
+
+		elseIf, ok := n.Else.(*ast.IfBlock)
+		if !ok {
+			break
+		}
+		assert.AssertNotNil(elseIf.Cond)
+		v.directive(exprPos(elseIf.Cond), elseIf.Cond.Code)
+		v.line("} else if %s {", strings.TrimSpace(elseIf.Cond.Code))
+		n = elseIf
+	}
+	if els, ok := n.Else.(*ast.Fragment); ok {
+		v.line("} else {")
+		v.indent++
+		walk(v, els)
+		v.indent--
+	}
+	v.line("}")
+}
+
+// generatedIdents are the identifiers in scope of the generated
+// statements which the variables of each blocks may not shadow.
+var generatedIdents = []string{"c", "child", "parent", "props", "renderer", "roots", "slot", "slots"}
+
+// walkEachBlock writes a range loop creating the components
+// of every iteration, followed by an if statement creating
+// those of the else branch if there were no iterations, bound
+// to the expression ranged over, see openBlock.
+//
+// The elements of a keyed each block which is bound are kept for
+// their key as it is built anew, taking the attributes of those
+// built in their place. Their attributes are then set as they are
+// built rather than bound, the block being bound to them instead.
+func walkEachBlock(v *walker, n *ast.EachBlock) {
+	assert.AssertNotNil(n.Expr)
+
+	for _, ident := range []*ast.Ident{n.Value, n.Index} {
+		if ident != nil && slices.Contains(generatedIdents, ident.Name) {
+			v.errorf(ident.Pos(), "each variable %s shadows the generated identifier of the same name", ident.Name)
+			return
+		}
+	}
+
+	if n.Key != nil {
+		if len(n.Body.Nodes) != 1 {
+			v.errorf(n.Pos(), "keyed each block must contain a single element")
+			return
+		}
+		if _, ok := n.Body.Nodes[0].(*ast.Element); !ok {
+			v.errorf(n.Body.Nodes[0].Pos(), "keyed each block must contain a single element")
+			return
+		}
+	}
+
+	// Unused variables are left out, as Go does not allow them
+	value, index := "_", "_"
+	if n.Value != nil && usesIdent(n.Body, n.Key, n.Value.Name) {
+		value = n.Value.Name
+	}
+	if n.Index != nil && usesIdent(n.Body, n.Key, n.Index.Name) {
+		index = n.Index.Name
+	}
+
+	codes := []string{n.Expr.Code}
+	if n.Key != nil {
+		for _, attr := range n.Body.Nodes[0].(*ast.Element).Attrs {
+			if attr.ValueExpr != nil {
+				codes = append(codes, attr.ValueExpr.Code)
+			}
+		}
+	}
+
+	v.separate()
+	closeBlock, bound := v.openBlock(n.Pos(), codes...)
+	defer closeBlock()
+
+	empty := ""
+	if n.Else != nil {
+		empty = fmt.Sprintf("empty%d", v.idCounter.Add(1))
+		v.line("%s := true", empty)
+	}
+
+	expr := strings.TrimSpace(n.Expr.Code)
+	v.directive(exprPos(n.Expr), expr)
+	switch {
+	case index != "_":
+		v.line("for %s, %s := range %s {", index, value, expr)
+	case value != "_":
+		v.line("for _, %s := range %s {", value, expr)
+	default:
+		v.line("for range %s {", expr)
+	}
+	v.indent++
+	if empty != "" {
+		v.line("%s = false", empty)
+	}
+	v.key, v.keyBound = n.Key, bound
+	walk(v, n.Body)
+	v.indent--
+	v.line("}")
+
+	if n.Else != nil {
+		v.line("if %s {", empty)
+		v.indent++
+		walk(v, n.Else)
+		v.indent--
+		v.line("}")
+	}
+}
+
+// walkComponent writes the invocation of the tree function of
+// the component used by el, appending the components it returns
+// to the current component or, at the top level, to the roots.
+func walkComponent(v *walker, el *ast.Element) {
+	name := el.Name.Name
+	if len(el.Directives) > 0 {
+		d := el.Directives[0]
+		v.errorf(d.Pos(), "directive %s:%s cannot be used on component %s", d.Kind.Name, d.Name.Name, name)
+		return
+	}
+	if v.key != nil {
+		v.errorf(el.Pos(), "keyed each block must contain an element rather than component %s", name)
+		return
+	}
+
+	ref, err := v.resolveComponent(name)
+	if err != nil {
+		v.errorf(el.Name.Pos(), "%s", err)
+		return
+	}
+	errs := len(v.errs)
+	props := ref.props(el, v.errorf)
+	slots := slotContents(el.Nodes, v.errorf)
+	if len(v.errs) > errs {
+		return
+	}
+
+	v.separate()
+	id := fmt.Sprintf("%s%d", goIdent(name), v.idCounter.Add(1))
+	tree, propsType := ref.qualify(ref.TreeName()), ref.qualify(ref.PropsName())
+	v.directive(el.LeftChevron, "")
+	switch {
+	case len(props) == 0 && len(slots) == 0:
+		v.line("%s := %s(renderer, %s{}, nil)", id, tree, propsType)
+	case len(props) == 0:
+		v.line("%s := %s(renderer, %s{}, render.Slots{", id, tree, propsType)
+	default:
+		v.line("%s := %s(renderer, %s{", id, tree, propsType)
+		v.indent++
+		for _, p := range props {
+			v.directive(p.pos, p.anchor)
+			v.line("%s: %s,", p.name, p.value)
+		}
+		v.indent--
+		if len(slots) == 0 {
+			v.line("}, nil)")
+		} else {
+			v.line("}, render.Slots{")
+		}
+	}
+
+	if len(slots) > 0 {
+		// Slot content is rendered by the component into
+		// the component passed as parent
+		v.indent++
+		v.compStack = append(v.compStack, "parent")
+		for _, slot := range slots {
+			v.line("%s: func(parent render.Component) {", strconv.Quote(slot.name))
+			v.indent++
+			walkList(v, slot.nodes)
+			v.indent--
+			v.line("},")
+		}
+		v.compStack = v.compStack[:len(v.compStack)-1]
+		v.indent--
+		v.line("})")
+	}
+
+	if len(v.compStack) > 0 {
+		v.line("for _, child := range %s {", id)
+		v.indent++
+		v.line("renderer.Append(%s, child)", v.curCompId())
+		v.indent--
+		v.line("}")
+		return
+	}
+	v.line("roots = append(roots, %s...)", id)
+	v.roots++
+}
+
+// slotContent is the markup passed to the slot of a component.
+type slotContent struct {
+	name  string
+	nodes []ast.RenderNode
+}
+
+// slotContents groups the children of an element using a
+// component by the slot they are passed to, in the order
+// the slots are first passed content. Elements are passed
+// to the slot named by their slot attribute, which is left
+// out, and all other children to the default slot. Slot
+// attributes which are not string literals are reported
+// to errorf.
+func slotContents(children []ast.RenderNode, errorf func(pos source.Pos, format string, a ...any)) []slotContent {
+	slots := make([]slotContent, 0)
+	add := func(name string, node ast.RenderNode) {
+		for i := range slots {
+			if slots[i].name == name {
+				slots[i].nodes = append(slots[i].nodes, node)
+				return
+			}
+		}
+		slots = append(slots, slotContent{name: name, nodes: []ast.RenderNode{node}})
+	}
+
+	for _, child := range children {
+		el, ok := child.(*ast.Element)
+		if !ok {
+			add("", child)
+			continue
+		}
+
+		i := slices.IndexFunc(el.Attrs, func(attr *ast.Attribute) bool {
+			return attr.Name.Name == ast.SlotAttribute
+		})
+		if i < 0 {
+			add("", child)
+			continue
+		}
+		if el.Attrs[i].ValueExpr != nil {
+			errorf(el.Attrs[i].Pos(), "slot attribute of %s must be a string literal", el.Name.Name)
+			continue
+		}
+
+		passed := *el
+		passed.Attrs = slices.Delete(slices.Clone(el.Attrs), i, i+1)
+		add(el.Attrs[i].ValueLiteral, &passed)
+	}
+
+	return slots
+}
+
+// walkSlot writes the rendering of the content passed to
+// the slot into the current component, or of the slot's
+// fallback if none was passed.
+func walkSlot(v *walker, n *ast.Slot) {
+	if len(v.compStack) == 0 {
+		v.errorf(n.Pos(), "slot must be inside an element")
+		return
+	}
+
+	v.separate()
+	v.directive(n.LeftChevron, "")
+	v.line("if slot := slots[%s]; slot != nil {", strconv.Quote(n.Name))
+	v.indent++
+	v.line("slot(%s)", v.curCompId())
+	v.indent--
+	if len(n.Fallback.Nodes) > 0 {
+		v.line("} else {")
+		v.indent++
+		walk(v, n.Fallback)
+		v.indent--
+	}
+	v.line("}")
+}
+
+// resolveComponent resolves the tag name of an element using
+// a component. Unqualified names refer to the components of
+// the package, qualified names to those of a package imported
+// by the code block.
+func (w *walker) resolveComponent(name string) (*componentRef, error) {
+	pkgName, compName, qualified := strings.Cut(name, ".")
+	if !qualified {
+		comp, ok := w.comps[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown component %s", name)
+		}
+		return &componentRef{component: comp}, nil
+	}
+
+	if !source.IsIdentifier(pkgName) || !source.IsIdentifier(compName) {
+		return nil, fmt.Errorf("invalid component name %s", name)
+	}
+	a := &propAnalyzer{cb: w.code}
+	if _, ok := a.importPath(pkgName); !ok {
+		return nil, fmt.Errorf("component %s refers to package %s which is not imported", name, pkgName)
+	}
+	return &componentRef{Pkg: pkgName, component: &component{Name: compName}}, nil
+}
+
+// goToken is a token of Go code.
+type goToken struct {
+	tok source.Token
+	lit string
+}
+
+// goTokens returns the tokens of the Go code.
+func goTokens(code string) []goToken {
+	file := source.NewFileSet().AddFile("", 1, len(code))
+	var s scanner.Scanner
+	s.Init(file, []byte(code), nil, 0)
+	toks := make([]goToken, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == source.EOF {
+			return toks
+		}
+		toks = append(toks, goToken{tok, lit})
+	}
+}
+
+// fieldDeps returns the fields of the component instance c which
+// the Go code depends on, reporting whether it refers to c at all.
+// Code using c other than by selecting one of its fields, such as
+// by calling one of its methods, depends on the whole instance, for
+// which nil fields are returned.
+func fieldDeps(code string) ([]string, bool) {
+	toks := goTokens(code)
+	deps := make([]string, 0)
+	refers := false
+	for i, t := range toks {
+		if t.tok != source.IDENT || t.lit != "c" || i > 0 && toks[i-1].tok == source.PERIOD {
+			continue
+		}
+		refers = true
+		if i+2 >= len(toks) || toks[i+1].tok != source.PERIOD || toks[i+2].tok != source.IDENT {
+			return nil, true
+		}
+		if i+3 < len(toks) && toks[i+3].tok == source.LPAREN {
+			return nil, true
+		}
+		if !slices.Contains(deps, toks[i+2].lit) {
+			deps = append(deps, toks[i+2].lit)
+		}
+	}
+	return deps, refers
+}
+
+// hasCall reports whether the Go code calls a function, which
+// may read signals, or converts a value.
+func hasCall(code string) bool {
+	toks := goTokens(code)
+	for i := 1; i < len(toks); i++ {
+		if toks[i].tok != source.LPAREN {
+			continue
+		}
+		switch toks[i-1].tok {
+		case source.IDENT, source.RPAREN, source.RBRACK, source.RBRACE:
+			return true
+		}
+	}
+	return false
+}
+
+// usesIdent reports whether the Go code of body or key
+// refers to the identifier name.
+func usesIdent(body *ast.Fragment, key *ast.Expr, name string) bool {
+	uses := func(code string) bool {
+		file := source.NewFileSet().AddFile("", 1, len(code))
+		var s scanner.Scanner
+		s.Init(file, []byte(code), nil, 0)
+		for {
+			_, tok, lit := s.Scan()
+			if tok == source.EOF {
+				return false
+			}
+			if tok == source.IDENT && lit == name {
+				return true
+			}
+		}
+	}
+
+	if key != nil && uses(key.Code) {
+		return true
+	}
+
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if expr, ok := n.(*ast.Expr); ok && uses(expr.Code) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
 func walk(v *walker, node ast.Node) {
-	if comp, ok := node.(*ast.Element); ok {
-		id := fmt.Sprintf("%s%d", comp.Name.Name, v.idCounter.Add(1))
+	if el, ok := node.(*ast.Element); ok && isComponentName(el.Name.Name) {
+		walkComponent(v, el)
+		return
+	}
+
+	id := ""
+	switch n := node.(type) {
+	case *ast.Element:
+		id = fmt.Sprintf("%s%d", goIdent(n.Name.Name), v.idCounter.Add(1))
+	case *ast.Text, *ast.Expr:
+		// Text outside of elements is reported by Visit
+		if len(v.compStack) > 0 {
+			id = fmt.Sprintf("text%d", v.idCounter.Add(1))
+		}
+	}
+	if id != "" {
 		v.compStack = append(v.compStack, id)
 		defer func() {
 			v.compStack = slices.Delete(v.compStack, len(v.compStack)-1, len(v.compStack))
@@ -171,10 +916,20 @@ func walk(v *walker, node ast.Node) {
 	case *ast.Element:
 		walk(v, n.Name)
 		walkList(v, n.Attrs)
+		walkList(v, n.Directives)
+		v.appendComp()
 		walkList(v, n.Nodes)
+	case *ast.IfBlock:
+		walkIfBlock(v, n)
+	case *ast.EachBlock:
+		walkEachBlock(v, n)
+	case *ast.Slot:
+		walkSlot(v, n)
 	case *ast.Attribute:
 		walk(v, n.Name)
+	case *ast.Directive:
 	case *ast.Text:
+	case *ast.Expr:
 	case *ast.Ident:
 	default:
 		return
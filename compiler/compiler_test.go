@@ -2,11 +2,21 @@ package compiler
 
 import (
 	"fmt"
+	goast "go/ast"
+	"go/format"
+	goparser "go/parser"
 	source "go/token"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/ast"
 	"github.com/tifye/flamingo/parser"
 )
 
@@ -16,8 +26,493 @@ func TestCompiler(t *testing.T) {
 	root, err := parser.ParseFile(fset, "testdata/Mino.flamingo", nil)
 	assert.NoError(t, err)
 
-	err = CompileFile("main", "Mino", root, output)
+	err = CompileFile("main", fset, "Mino", root, output)
 	assert.NoError(t, err)
 
 	fmt.Println(output.String())
+
+	_, err = goparser.ParseFile(source.NewFileSet(), "", output.String(), 0)
+	assert.NoError(t, err, "expected generated code to be valid Go")
+
+	formatted, err := format.Source([]byte(output.String()))
+	require.NoError(t, err)
+	assert.Equal(t, string(formatted), output.String(), "expected generated code to be formatted")
+}
+
+// compile compiles input as the component name, resolving the components
+// used by its template among comps, and checks the output is valid Go.
+func compile(t *testing.T, name, input string, comps map[string]*component) (string, error) {
+	t.Helper()
+	root, err := parser.ParseFile(source.NewFileSet(), "", input)
+	require.NoError(t, err)
+
+	output := &strings.Builder{}
+	err = compileFile("main", nil, name, root, comps, output)
+	if err == nil {
+		_, perr := goparser.ParseFile(source.NewFileSet(), "", output.String(), 0)
+		require.NoError(t, perr, "expected generated code to be valid Go")
+	}
+	return output.String(), err
+}
+
+func TestCompileDirective(t *testing.T) {
+	out, err := compile(t, "Counter", `<button on:click={c.handleClick}>{c.count}</button>`, nil)
+	require.NoError(t, err)
+	assert.Contains(t, out, `.SetAttribute("on:click", c.handleClick)`)
+}
+
+func TestCompileCodeBlock(t *testing.T) {
+	t.Run("package and imports", func(t *testing.T) {
+		out, err := compile(t, "Mino", "---\npackage meep\n\nimport (\n\t\"fmt\"\n\t\"github.com/tifye/flamingo/render\"\n)\n\nfunc meep() { fmt.Println(render.Renderer(nil)) }\n---\n<div></div>", nil)
+		require.NoError(t, err)
+
+		file, err := goparser.ParseFile(source.NewFileSet(), "", out, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "meep", file.Name.Name)
+		require.Len(t, file.Imports, 2)
+		assert.Equal(t, `"fmt"`, file.Imports[0].Path.Value)
+		assert.Equal(t, `"github.com/tifye/flamingo/render"`, file.Imports[1].Path.Value)
+		assert.Contains(t, out, "func meep()")
+	})
+
+	t.Run("missing package clause", func(t *testing.T) {
+		out, err := compile(t, "Mino", "---\nvar izu = 1\n---\n<div></div>", nil)
+		require.NoError(t, err)
+
+		file, err := goparser.ParseFile(source.NewFileSet(), "", out, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "main", file.Name.Name)
+		assert.Contains(t, out, "var izu = 1")
+	})
+
+	t.Run("referenced imports", func(t *testing.T) {
+		out, err := compile(t, "Mino", "---\nimport (\n\t\"fmt\"\n\t_ \"embed\"\n\t\"strings\"\n\t\"example.com/go-ui/v2\"\n)\n---\n<div title={strings.ToUpper(ui.Title)}></div>", nil)
+		require.NoError(t, err)
+
+		file, err := goparser.ParseFile(source.NewFileSet(), "", out, goparser.ImportsOnly)
+		require.NoError(t, err)
+		paths := make([]string, 0)
+		for _, imp := range file.Imports {
+			paths = append(paths, imp.Path.Value)
+		}
+		assert.Equal(t, []string{`"embed"`, `"strings"`, `"example.com/go-ui/v2"`, `"github.com/tifye/flamingo/render"`}, paths, "expected unused imports to be dropped")
+	})
+
+	t.Run("import clash", func(t *testing.T) {
+		_, err := compile(t, "Mino", "---\nimport \"example.com/render\"\n---\n<div></div>", nil)
+		assert.ErrorContains(t, err, "clashes with generated import")
+	})
+}
+
+func TestCompileTopLevelText(t *testing.T) {
+	_, err := compile(t, "Mino", "mino <div></div>", nil)
+	assert.ErrorContains(t, err, `text "mino" must be inside an element`)
+
+	_, err = compile(t, "Mino", "<div></div> <p></p>", nil)
+	assert.NoError(t, err, "expected the space between top level elements to be ignored")
+}
+
+func TestCompileIfBlock(t *testing.T) {
+	out, err := compile(t, "Mino", "<div>{#if c.loading}<p>Loading</p>{:else if c.err != nil}<p>{c.err}</p>{:else}done{/if}</div>{#if c.footer}<footer></footer>{/if}", nil)
+	require.NoError(t, err)
+
+	file, err := goparser.ParseFile(source.NewFileSet(), "", out, 0)
+	require.NoError(t, err)
+	var ifs []*goast.IfStmt
+	goast.Inspect(file, func(n goast.Node) bool {
+		if stmt, ok := n.(*goast.IfStmt); ok {
+			ifs = append(ifs, stmt)
+		}
+		return true
+	})
+	// Both blocks, the else if branch and the Attach guard
+	require.Len(t, ifs, 4)
+	assert.Equal(t, 1, strings.Count(out, "c.Block("))
+	assert.Contains(t, out, "roots = append(roots, c.RootBlock([]string{\"footer\"}, func(parent render.Component) {", "expected the block at the top level to be bound among the roots")
+}
+
+func TestCompileEachBlock(t *testing.T) {
+	t.Run("unused variables", func(t *testing.T) {
+		out, err := compile(t, "Mino", "{#each c.items as item, i}<br/>{/each}{#each c.items as item, i}<p>{item}</p>{/each}", nil)
+		require.NoError(t, err)
+		assert.Contains(t, out, "for range c.items {")
+		assert.Contains(t, out, "for _, item := range c.items {")
+	})
+
+	t.Run("shadowed identifier", func(t *testing.T) {
+		for input, ident := range map[string]string{
+			"{#each c.items as c}<p>{c}</p>{/each}":                        "c",
+			"<div>{#each c.items as slot}<slot>{slot}</slot>{/each}</div>": "slot",
+			"<div>{#each c.items as child}<Mino/>{/each}</div>":            "child",
+		} {
+			_, err := compile(t, "Mino", input, nil)
+			assert.ErrorContains(t, err, "each variable "+ident+" shadows the generated identifier", input)
+		}
+	})
+
+	t.Run("keyed with several elements", func(t *testing.T) {
+		_, err := compile(t, "Mino", "{#each c.items as item (item)}<dt/><dd/>{/each}", nil)
+		assert.ErrorContains(t, err, "keyed each block must contain a single element")
+	})
+}
+
+func TestCompileComponent(t *testing.T) {
+	cb, err := parseCodeBlock("main", &ast.CodeBlock{Code: "type Counter struct {\n\tstart int `prop`\n\tlabel string `prop`\n}"})
+	require.NoError(t, err)
+	counter, err := analyzeComponent("Counter", cb)
+	require.NoError(t, err)
+	cb, err = parseCodeBlock("main", &ast.CodeBlock{Code: "type level uint8\ntype Toggle struct {\n\ton bool `prop`\n\tlevel level `prop`\n}"})
+	require.NoError(t, err)
+	toggle, err := analyzeComponent("Toggle", cb)
+	require.NoError(t, err)
+	comps := map[string]*component{"Counter": counter, "Toggle": toggle}
+
+	t.Run("imported component", func(t *testing.T) {
+		out, err := compile(t, "App", "---\nimport \"example.com/ui\"\n---\n<ui.Button text=\"ok\"/>", comps)
+		require.NoError(t, err)
+		assert.Contains(t, out, "ui.ButtonTree(renderer, ui.ButtonProps{")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			input string
+			err   string
+		}{
+			{`<Counter start={1} label="a" step={2}/>`, "component Counter has no prop Step"},
+			{`<Counter start="one" label="a"/>`, `cannot use "one" as the int value of prop Start of component Counter`},
+			{`<Toggle on="maybe" level="3"/>`, `cannot use "maybe" as the bool value of prop On of component Toggle`},
+			{`<Toggle on level="256"/>`, `cannot use "256" as the level value of prop Level of component Toggle`},
+			{`<Counter start={1}/>`, "missing prop Label of component Counter"},
+			{`<Counter start={1} Start={2} label="a"/>`, "prop Start of component Counter is set more than once"},
+			{`<Counter start={1} label="a"><p slot={c.slot}/></Counter>`, "slot attribute of p must be a string literal"},
+			{`<Counter on:click={c.click} start={1} label="a"/>`, "directive on:click cannot be used on component Counter"},
+			{`<Timer/>`, "unknown component Timer"},
+			{`<ui.Button/>`, "component ui.Button refers to package ui which is not imported"},
+			{`<slot/>`, "slot must be inside an element"},
+		}
+		for _, tt := range tests {
+			_, err := compile(t, "App", tt.input, comps)
+			assert.ErrorContains(t, err, tt.err, tt.input)
+		}
+	})
+
+	t.Run("positioned errors", func(t *testing.T) {
+		fset := source.NewFileSet()
+		root, err := parser.ParseFile(fset, "App.flamingo", "<main>\n  <Counter start={1} label=\"a\" step={2}/>\n  <Timer/>\n</main>")
+		require.NoError(t, err)
+
+		err = compileFile("main", fset, "App", root, comps, &strings.Builder{})
+		var errs parser.ErrorList
+		require.ErrorAs(t, err, &errs)
+		msgs := make([]string, len(errs))
+		for i, e := range errs {
+			msgs[i] = e.Error()
+		}
+		assert.Equal(t, []string{
+			"App.flamingo:2:32: component Counter has no prop Step",
+			"App.flamingo:3:4: unknown component Timer",
+		}, msgs, "expected all errors at their position")
+	})
+}
+
+func TestCompileLineDirectives(t *testing.T) {
+	input := "---\ntype Mino struct { count int\n\tLabel string `prop:\"\"`\n}\n---\n<ul>\n  {#each c.items as item}\n    <li>{item}</li>\n  {/each}\n  {#if c.count > 1}<Mino label={c.Label}/>{/if}\n</ul>"
+	fset := source.NewFileSet()
+	root, err := parser.ParseFile(fset, "views/Mino.flamingo", input)
+	require.NoError(t, err)
+
+	output := &strings.Builder{}
+	err = CompileFile("main", fset, "Mino", root, output)
+	require.NoError(t, err)
+	out := output.String()
+
+	assert.Contains(t, out, "//line Mino.flamingo:2:1\ntype Mino struct {\n\trender.Reactive\n//line Mino.flamingo:2:19\n\tcount int")
+	assert.Contains(t, out, "\n//line Mino.flamingo:7\n\t\tfor _, item := range\n//line Mino.flamingo:7:8\n\t\tc.items {\n", "expected an expression further into the line than its column to be moved to a line of its own")
+
+	formatted, err := format.Source([]byte(out))
+	require.NoError(t, err)
+	assert.Equal(t, string(formatted), out, "expected the code to be formatted")
+
+	// Positions of the generated code are those of the template
+	genFset := source.NewFileSet()
+	file, err := goparser.ParseFile(genFset, "Mino_flamingo.go", out, 0)
+	require.NoError(t, err)
+	positions := make(map[string][]source.Position)
+	goast.Inspect(file, func(n goast.Node) bool {
+		if expr, ok := n.(goast.Expr); ok {
+			var code strings.Builder
+			require.NoError(t, format.Node(&code, genFset, expr))
+			positions[code.String()] = append(positions[code.String()], genFset.Position(expr.Pos()))
+		}
+		return true
+	})
+	at := func(code string) []string {
+		at := make([]string, 0)
+		for _, pos := range positions[code] {
+			at = append(at, pos.String())
+		}
+		return at
+	}
+	assert.Equal(t, []string{"Mino.flamingo:7:10"}, at("c.items"))
+	assert.Contains(t, at("item"), "Mino.flamingo:8:10")
+	assert.Equal(t, []string{"Mino.flamingo:10:8"}, at("c.count > 1"))
+	assert.Equal(t, []string{"Mino.flamingo:10:33"}, at("c.Label"))
+	assert.Contains(t, at("string"), "Mino.flamingo:3:8", "expected the prop type to be at its field")
+
+	// Code generated past a node is mapped back to the generated code
+	assert.Equal(t, "Mino_flamingo.go", positions["renderer.Append"][0].Filename)
+	lines := strings.Count(input, "\n") + 1
+	for code, all := range positions {
+		for _, pos := range all {
+			if pos.Filename == "Mino.flamingo" {
+				assert.LessOrEqual(t, pos.Line, lines, "expected %s to be within the template", code)
+			}
+		}
+	}
+
+	// No directive is written inside a string spanning several lines
+	fset = source.NewFileSet()
+	root, err = parser.ParseFile(fset, "Raw.flamingo", "<div>{`a\nb`}</div>")
+	require.NoError(t, err)
+	output.Reset()
+	err = CompileFile("main", fset, "Raw", root, output)
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), "`a\nb`)\n", "expected the string to be left intact")
+}
+
+func TestCompileDir(t *testing.T) {
+	counter := "---\ntype Counter struct {\n\tcount int\n}\n---\n<button>{c.count}</button>"
+
+	t.Run("writes generated files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"Counter.flamingo": {Data: []byte(counter)},
+			"App.flamingo":     {Data: []byte("<main><Counter/></main>")},
+			"main.go":          {Data: []byte("package main\n\nfunc main() {}\n")},
+		}
+		sink := &memSink{}
+		err := CompileDir("main", source.NewFileSet(), fsys, sink)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"App_flamingo.go", "Counter_flamingo.go"}, slices.Collect(maps.Keys(sink.files)))
+		assert.True(t, strings.HasPrefix(sink.files["App_flamingo.go"], generatedHeader))
+		assert.Contains(t, sink.files["App_flamingo.go"], "\n//line App.flamingo:1:6\n\tCounter2 := CounterTree(")
+	})
+
+	t.Run("errors of all templates", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"Counter.flamingo": {Data: []byte(counter)},
+			"App.flamingo":     {Data: []byte("<main><Card/></main>")},
+			"Bad.flamingo":     {Data: []byte("<div></span>")},
+			"Meep.flamingo":    {Data: []byte("---\ntype Meep int\n---\n<p></p>")},
+		}
+		sink := &memSink{}
+		err := CompileDir("main", source.NewFileSet(), fsys, sink)
+		var errs parser.ErrorList
+		require.ErrorAs(t, err, &errs)
+		assert.Empty(t, sink.files, "expected nothing to be written")
+
+		files := make([]string, len(errs))
+		for i, e := range errs {
+			files[i] = e.Pos.Filename
+		}
+		assert.Equal(t, []string{"Bad.flamingo", "Meep.flamingo"}, slices.Compact(files), "expected the parse errors of each template")
+	})
+
+	t.Run("removes stale files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"Counter.flamingo":    {Data: []byte(counter)},
+			"Counter_flamingo.go": {Data: []byte(generatedHeader + "\npackage main\n\nfunc stale() {}\n")},
+			"Old_flamingo.go":     {Data: []byte(generatedHeader + "\npackage main\n\ntype Old struct{}\n")},
+			"hand_flamingo.go":    {Data: []byte("package main\n")},
+		}
+		sink := &memSink{}
+		err := CompileDir("main", source.NewFileSet(), fsys, sink)
+		require.NoError(t, err)
+		assert.Contains(t, sink.files, "Counter_flamingo.go")
+		assert.Equal(t, []string{"Old_flamingo.go"}, sink.removed, "expected only stale generated files to be removed")
+	})
+
+	t.Run("type errors", func(t *testing.T) {
+		sink := &memSink{}
+		err := CompileDir("main", source.NewFileSet(), os.DirFS("testdata/check/invalid"), sink)
+		var errs parser.ErrorList
+		require.ErrorAs(t, err, &errs)
+		assert.Empty(t, sink.files, "expected nothing to be written")
+
+		msgs := make([]string, len(errs))
+		for i, e := range errs {
+			msgs[i] = e.Error()
+		}
+		assert.Equal(t, []string{
+			"Counter.flamingo:7:4: c.cuont undefined (type *Counter has no field or method cuont)",
+			"Counter.flamingo:10:35: c.Count undefined (type *Counter has no field or method Count, but does have field count)",
+		}, msgs)
+	})
+
+	t.Run("package clause mismatch", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"Label.flamingo": {Data: []byte("<p></p>")},
+			"widgets.go":     {Data: []byte("package widgets\n")},
+		}
+		sink := &memSink{}
+		err := CompileDir("main", source.NewFileSet(), fsys, sink)
+		assert.EqualError(t, err, "widgets.go:1:9: package widgets; expected package main")
+		assert.Empty(t, sink.files, "expected nothing to be written")
+	})
+
+	t.Run("package of the directory", func(t *testing.T) {
+		tests := []struct {
+			name string
+			fsys fstest.MapFS
+			pkg  string
+		}{
+			{"go files", fstest.MapFS{
+				"Label.flamingo":    {Data: []byte("<p></p>")},
+				"widgets.go":        {Data: []byte("package widgets\n")},
+				"widgets_test.go":   {Data: []byte("package widgets_test\n")},
+				"Label_flamingo.go": {Data: []byte(generatedHeader + "\npackage main\n")},
+			}, "widgets"},
+			{"declared by templates", fstest.MapFS{
+				"Label.flamingo": {Data: []byte("<p></p>")},
+				"Title.flamingo": {Data: []byte("---\npackage widgets\n---\n<h1></h1>")},
+			}, "widgets"},
+			{"main", fstest.MapFS{
+				"Label.flamingo": {Data: []byte("<p></p>")},
+			}, "main"},
+		}
+		for _, tt := range tests {
+			sink := &memSink{}
+			err := CompileDir("", source.NewFileSet(), tt.fsys, sink)
+			require.NoError(t, err, tt.name)
+			assert.Contains(t, sink.files["Label_flamingo.go"], "\npackage "+tt.pkg+"\n", tt.name)
+		}
+	})
+
+	t.Run("template declaring another package", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"Label.flamingo": {Data: []byte("---\npackage main\n---\n<p></p>")},
+			"widgets.go":     {Data: []byte("package widgets\n")},
+		}
+		err := CompileDir("", source.NewFileSet(), fsys, &memSink{})
+		assert.EqualError(t, err, "Label.flamingo:2:9: package main; expected package widgets")
+	})
+
+	t.Run("type checks with package files", func(t *testing.T) {
+		sink := &memSink{}
+		err := CompileDir("main", source.NewFileSet(), os.DirFS("testdata/check/valid"), sink)
+		require.NoError(t, err)
+		assert.Len(t, sink.files, 2)
+	})
+}
+
+func TestE2EGenerated(t *testing.T) {
+	sink := &memSink{}
+	err := CompileDir("", source.NewFileSet(), os.DirFS("../e2e"), sink)
+	require.NoError(t, err)
+	assert.Empty(t, sink.removed)
+	for name, src := range sink.files {
+		disk, err := os.ReadFile(filepath.Join("../e2e", name))
+		require.NoError(t, err)
+		assert.Equal(t, src, string(disk), "expected %s to be up to date, run go generate ./e2e", name)
+	}
+}
+
+type memSink struct {
+	files   map[string]string
+	removed []string
+}
+
+func (s *memSink) WriteFile(name string, data []byte) error {
+	if s.files == nil {
+		s.files = map[string]string{}
+	}
+	s.files[name] = string(data)
+	return nil
+}
+
+func (s *memSink) Remove(name string) error {
+	s.removed = append(s.removed, name)
+	return nil
+}
+
+func TestCompileReactive(t *testing.T) {
+	t.Run("embedded reactive", func(t *testing.T) {
+		out, err := compile(t, "Counter", "---\ntype Counter struct {\n\tcount int\n}\n---\n<button title={title}>{c.count}</button>", nil)
+		require.NoError(t, err)
+		assert.Contains(t, out, "type Counter struct {\n\trender.Reactive\n\n\tcount int\n}")
+		assert.Contains(t, out, "\tc := &Counter{\n\t\tReactive: render.NewReactive(renderer),\n\t}\n")
+		assert.NotContains(t, out, "title)\n\t})", "expected attributes not depending on c to be set once")
+	})
+
+	t.Run("generated struct", func(t *testing.T) {
+		out, err := compile(t, "Counter", "<p>{format(c)}</p>", nil)
+		require.NoError(t, err)
+		assert.Contains(t, out, "type Counter struct {\n\trender.Reactive\n}")
+		assert.Contains(t, out, "\tc.Bind(nil, func() {\n")
+	})
+
+	t.Run("signals", func(t *testing.T) {
+		out, err := compile(t, "Counter", "<p title={theme.Get()}>{(a + b)}</p>", nil)
+		require.NoError(t, err)
+		assert.Contains(t, out, "c.Bind([]string{}, func() {", "expected function calls to be bound for the signals they read")
+		assert.Equal(t, 1, strings.Count(out, "c.Bind("))
+	})
+
+	t.Run("clashes", func(t *testing.T) {
+		_, err := compile(t, "Counter", "---\ntype Counter struct {\n\tUpdate func()\n}\n---\n", nil)
+		assert.ErrorContains(t, err, "field Update of component Counter clashes with the embedded render.Reactive")
+
+		_, err = compile(t, "Counter", "---\ntype Counter struct{}\n\nfunc (c *Counter) Invalidate() {}\n---\n", nil)
+		assert.ErrorContains(t, err, "method Invalidate of component Counter clashes with the method of the embedded render.Reactive")
+	})
+}
+
+func TestImportName(t *testing.T) {
+	for path, want := range map[string]string{
+		"fmt":                        "fmt",
+		"net/http":                   "http",
+		"gopkg.in/yaml.v3":           "yaml",
+		"github.com/mattn/go-isatty": "isatty",
+		"github.com/go-chi/chi/v5":   "chi",
+		"example.com/some-lib":       "some",
+	} {
+		assert.Equal(t, want, importSpec{Path: path}.name(), path)
+	}
+	assert.Equal(t, "r", importSpec{Name: "r", Path: "github.com/tifye/flamingo/render"}.name())
+}
+
+func TestFieldDeps(t *testing.T) {
+	tests := []struct {
+		code   string
+		deps   []string
+		refers bool
+	}{
+		{"count", []string{}, false},
+		{"c.count", []string{"count"}, true},
+		{"c.a + c.b.c", []string{"a", "b"}, true},
+		{"c.a + c.b(c.a)", nil, true},
+		{"c.full()", nil, true},
+		{"x.c.d", []string{}, false},
+		{"format(c)", nil, true},
+		{`c.a + "c.b"`, []string{"a"}, true},
+	}
+	for _, tt := range tests {
+		deps, refers := fieldDeps(tt.code)
+		assert.Equal(t, tt.deps, deps, tt.code)
+		assert.Equal(t, tt.refers, refers, tt.code)
+	}
+}
+
+func TestHasCall(t *testing.T) {
+	tests := map[string]bool{
+		"count":           false,
+		"(a + b) * c":     false,
+		"signal.Get()":    true,
+		"fns[0]()":        true,
+		"string(b)":       true,
+		"func() int {}()": true,
+		`"fmt.Sprint(a)"`: false,
+	}
+	for code, expected := range tests {
+		assert.Equal(t, expected, hasCall(code), code)
+	}
 }
@@ -0,0 +1,472 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	"go/printer"
+	gotoken "go/token"
+	"math"
+	pathpkg "path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tifye/flamingo/ast"
+)
+
+// nonCopyable lists, per import path, the types which must not
+// be copied after first use and therefore cannot be props.
+var nonCopyable = map[string][]string{
+	"sync":        {"Cond", "Map", "Mutex", "Once", "Pool", "RWMutex", "WaitGroup"},
+	"sync/atomic": {"Bool", "Int32", "Int64", "Pointer", "Uint32", "Uint64", "Uintptr", "Value"},
+	"strings":     {"Builder"},
+}
+
+// prop is a component struct field tagged with `prop`
+// which is copied from the component's Props struct.
+type prop struct {
+	Field   string // name of the field on the component struct
+	Name    string // exported name of the field on the Props struct
+	Type    string
+	TypePos gotoken.Pos // position of the type in the code block
+	// Basic is the predeclared type underlying Type, which
+	// literal attributes are converted to, or empty if none
+	Basic string
+}
+
+// reactiveMembers are the fields and methods a component struct
+// gets from embedding render.Reactive, which it may not declare.
+var reactiveMembers = []string{"Reactive", "Bind", "SetRoot", "Invalidate", "Update"}
+
+// component describes the Go types generated for, or
+// declared by, a component's code block.
+type component struct {
+	Name string
+	// Declared reports whether the component struct is
+	// declared in the code block rather than generated.
+	Declared bool
+	// Lbrace is the position in the code block of the
+	// opening brace of the fields of the declared struct.
+	Lbrace gotoken.Pos
+	Props  []prop
+}
+
+func (c *component) PropsName() string { return c.Name + "Props" }
+func (c *component) FuncName() string  { return c.Name + "Comp" }
+func (c *component) TreeName() string  { return c.Name + "Tree" }
+
+// analyzeComponent inspects the code block for the component
+// struct named name and collects its props.
+func analyzeComponent(name string, cb *codeBlock) (*component, error) {
+	comp := &component{Name: name}
+
+	types := make(map[string]*goast.TypeSpec)
+	for _, decl := range cb.file.Decls {
+		for _, ident := range declaredNames(decl) {
+			switch ident.Name {
+			case comp.PropsName(), comp.FuncName(), comp.TreeName():
+				return nil, fmt.Errorf("%s is generated for component %s and cannot be declared in its code block", ident.Name, name)
+			}
+		}
+
+		gen, ok := decl.(*goast.GenDecl)
+		if !ok || gen.Tok != gotoken.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*goast.TypeSpec)
+			types[ts.Name.Name] = ts
+		}
+	}
+
+	for _, decl := range cb.file.Decls {
+		if fn, ok := decl.(*goast.FuncDecl); ok && fn.Recv != nil && receiverName(fn.Recv) == name &&
+			slices.Contains(reactiveMembers, fn.Name.Name) {
+			return nil, fmt.Errorf("method %s of component %s clashes with the method of the embedded render.Reactive", fn.Name.Name, name)
+		}
+
+		gen, ok := decl.(*goast.GenDecl)
+		if ok && gen.Tok == gotoken.TYPE {
+			continue
+		}
+		for _, ident := range declaredNames(decl) {
+			if ident.Name == name {
+				return nil, fmt.Errorf("%s is the name of the component and must be declared as a struct type", name)
+			}
+		}
+	}
+
+	ts, ok := types[name]
+	if !ok {
+		return comp, nil
+	}
+	comp.Declared = true
+
+	if ts.TypeParams != nil {
+		return nil, fmt.Errorf("component %s cannot have type parameters", name)
+	}
+	st, ok := ts.Type.(*goast.StructType)
+	if !ok || ts.Assign.IsValid() {
+		return nil, fmt.Errorf("component %s must be declared as a struct type", name)
+	}
+
+	comp.Lbrace = st.Fields.Opening
+
+	a := &propAnalyzer{cb: cb, types: types}
+	byName := make(map[string]string)
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			if slices.Contains(reactiveMembers, ident.Name) {
+				return nil, fmt.Errorf("field %s of component %s clashes with the embedded render.Reactive", ident.Name, name)
+			}
+		}
+		if len(field.Names) == 0 && slices.Contains(reactiveMembers, embeddedName(field.Type)) {
+			return nil, fmt.Errorf("field %s of component %s clashes with the embedded render.Reactive", embeddedName(field.Type), name)
+		}
+
+		if field.Tag == nil || !isPropTag(field.Tag.Value) {
+			continue
+		}
+
+		names := make([]string, 0, len(field.Names))
+		for _, ident := range field.Names {
+			names = append(names, ident.Name)
+		}
+		if len(names) == 0 {
+			names = append(names, embeddedName(field.Type))
+		}
+
+		typ, err := a.typeString(field.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		if nc := a.nonCopyableType(field.Type, map[string]bool{}); nc != "" {
+			if nc == typ {
+				return nil, fmt.Errorf("prop %s of component %s: type %s cannot be copied", names[0], name, typ)
+			}
+			return nil, fmt.Errorf("prop %s of component %s: type %s cannot be copied as it contains %s", names[0], name, typ, nc)
+		}
+
+		for _, fieldName := range names {
+			propName, err := exportName(fieldName)
+			if err != nil {
+				return nil, fmt.Errorf("prop %s of component %s: %w", fieldName, name, err)
+			}
+			if other, ok := byName[propName]; ok {
+				return nil, fmt.Errorf("prop %s of component %s clashes with prop %s", fieldName, name, other)
+			}
+			byName[propName] = fieldName
+
+			comp.Props = append(comp.Props, prop{
+				Field:   fieldName,
+				Name:    propName,
+				Type:    typ,
+				TypePos: field.Type.Pos(),
+				Basic:   a.basicType(field.Type, map[string]bool{}),
+			})
+		}
+	}
+
+	return comp, nil
+}
+
+// isPropTag reports whether the raw struct tag marks a prop,
+// either as the bare tag `prop` or with the key prop:"".
+func isPropTag(raw string) bool {
+	tag, err := strconv.Unquote(raw)
+	if err != nil {
+		return false
+	}
+	if tag == "prop" {
+		return true
+	}
+	_, ok := reflect.StructTag(tag).Lookup("prop")
+	return ok
+}
+
+// exportName returns name with its first letter upper cased.
+func exportName(name string) (string, error) {
+	r, size := utf8.DecodeRuneInString(name)
+	if !unicode.IsLetter(r) {
+		return "", fmt.Errorf("cannot derive an exported prop name from %s", name)
+	}
+	return string(unicode.ToUpper(r)) + name[size:], nil
+}
+
+// embeddedName returns the field name of an embedded field.
+func embeddedName(expr goast.Expr) string {
+	switch e := expr.(type) {
+	case *goast.StarExpr:
+		return embeddedName(e.X)
+	case *goast.SelectorExpr:
+		return e.Sel.Name
+	case *goast.IndexExpr:
+		return embeddedName(e.X)
+	case *goast.IndexListExpr:
+		return embeddedName(e.X)
+	case *goast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// receiverName returns the name of the type of the receiver
+// of a method, or an empty string if it cannot be determined.
+func receiverName(recv *goast.FieldList) string {
+	if len(recv.List) != 1 {
+		return ""
+	}
+	return embeddedName(recv.List[0].Type)
+}
+
+// declaredNames returns the package level identifiers
+// declared by decl. Methods declare no package level names.
+func declaredNames(decl goast.Decl) []*goast.Ident {
+	var names []*goast.Ident
+	switch d := decl.(type) {
+	case *goast.FuncDecl:
+		if d.Recv == nil {
+			names = append(names, d.Name)
+		}
+	case *goast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *goast.TypeSpec:
+				names = append(names, s.Name)
+			case *goast.ValueSpec:
+				names = append(names, s.Names...)
+			}
+		}
+	}
+	return names
+}
+
+type propAnalyzer struct {
+	cb    *codeBlock
+	types map[string]*goast.TypeSpec
+}
+
+func (a *propAnalyzer) typeString(expr goast.Expr) (string, error) {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, a.cb.fset, expr); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// nonCopyableType returns the name of a type which must not be
+// copied contained in values of the type expr, or an empty string
+// if there is none. Types declared in other packages are assumed
+// copyable unless listed in nonCopyable.
+func (a *propAnalyzer) nonCopyableType(expr goast.Expr, seen map[string]bool) string {
+	switch e := expr.(type) {
+	case *goast.ParenExpr:
+		return a.nonCopyableType(e.X, seen)
+	case *goast.IndexExpr:
+		return a.nonCopyableType(e.X, seen)
+	case *goast.IndexListExpr:
+		return a.nonCopyableType(e.X, seen)
+	case *goast.ArrayType:
+		if e.Len == nil {
+			return ""
+		}
+		return a.nonCopyableType(e.Elt, seen)
+	case *goast.StructType:
+		for _, field := range e.Fields.List {
+			if name := a.nonCopyableType(field.Type, seen); name != "" {
+				return name
+			}
+		}
+	case *goast.Ident:
+		ts, ok := a.types[e.Name]
+		if !ok || seen[e.Name] {
+			return ""
+		}
+		seen[e.Name] = true
+		return a.nonCopyableType(ts.Type, seen)
+	case *goast.SelectorExpr:
+		x, ok := e.X.(*goast.Ident)
+		if !ok {
+			return ""
+		}
+		path, ok := a.importPath(x.Name)
+		if !ok {
+			return ""
+		}
+		if slices.Contains(nonCopyable[path], e.Sel.Name) {
+			return x.Name + "." + e.Sel.Name
+		}
+	}
+	return ""
+}
+
+// basicTypes are the predeclared types which literal
+// attributes can be converted to, by their bit size.
+var basicTypes = map[string]int{
+	"bool": 0, "string": 0,
+	"int": 0, "int8": 8, "int16": 16, "int32": 32, "int64": 64, "rune": 32,
+	"uint": 0, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64, "uintptr": 0, "byte": 8,
+	"float32": 32, "float64": 64,
+}
+
+// basicType returns the predeclared type in basicTypes underlying
+// the type expr, or an empty string if there is none.
+func (a *propAnalyzer) basicType(expr goast.Expr, seen map[string]bool) string {
+	switch e := expr.(type) {
+	case *goast.ParenExpr:
+		return a.basicType(e.X, seen)
+	case *goast.Ident:
+		ts, ok := a.types[e.Name]
+		if !ok {
+			if _, ok := basicTypes[e.Name]; ok {
+				return e.Name
+			}
+			return ""
+		}
+		if seen[e.Name] || ts.TypeParams != nil {
+			return ""
+		}
+		seen[e.Name] = true
+		return a.basicType(ts.Type, seen)
+	}
+	return ""
+}
+
+// importPath resolves a package name used in the code
+// block to the path it was imported from.
+func (a *propAnalyzer) importPath(name string) (string, bool) {
+	for _, imp := range a.cb.imports {
+		if imp.Name == name || imp.Name == "" && pathpkg.Base(imp.Path) == name {
+			return imp.Path, true
+		}
+	}
+	return "", false
+}
+
+// isComponentName reports whether the tag name of an element
+// refers to a component rather than a DOM element, that is
+// whether it is capitalized after any package qualifier.
+func isComponentName(name string) bool {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// componentRef is a component used by a template. The props of
+// components of other packages are unknown to the compiler and
+// are checked by the Go compiler alone.
+type componentRef struct {
+	Pkg string // package name qualifying the component, empty if none
+	*component
+}
+
+func (r *componentRef) qualify(name string) string {
+	if r.Pkg == "" {
+		return name
+	}
+	return r.Pkg + "." + name
+}
+
+// propValue is a prop set by an attribute of an element
+// using a component, with its value as Go code.
+type propValue struct {
+	name  string
+	value string
+	// pos is the position in the template of anchor,
+	// the code of the value or else the prop name
+	pos    gotoken.Pos
+	anchor string
+}
+
+// props maps the attributes of el to the props of the component,
+// checking that every prop is set exactly once. Literal attributes
+// are converted to the basic type of their prop, if known, and are
+// strings otherwise. Errors are reported to errorf.
+func (r *componentRef) props(el *ast.Element, errorf func(pos gotoken.Pos, format string, a ...any)) []propValue {
+	props := make([]propValue, 0, len(el.Attrs))
+	set := make(map[string]bool)
+	for _, attr := range el.Attrs {
+		name, err := exportName(attr.Name.Name)
+		if err != nil || !gotoken.IsIdentifier(name) {
+			errorf(attr.Pos(), "attribute %s of component %s is not a valid prop name", attr.Name.Name, r.qualify(r.Name))
+			continue
+		}
+		var decl *prop
+		if r.Pkg == "" {
+			i := slices.IndexFunc(r.Props, func(p prop) bool { return p.Name == name })
+			if i < 0 {
+				errorf(attr.Pos(), "component %s has no prop %s", r.Name, name)
+				continue
+			}
+			decl = &r.Props[i]
+		}
+		if set[name] {
+			errorf(attr.Pos(), "prop %s of component %s is set more than once", name, r.qualify(r.Name))
+			continue
+		}
+		set[name] = true
+
+		p := propValue{
+			name:   name,
+			value:  strconv.Quote(attr.ValueLiteral),
+			pos:    attr.Name.Pos(),
+			anchor: name,
+		}
+		if attr.ValueExpr != nil {
+			p.value = strings.TrimSpace(attr.ValueExpr.Code)
+			p.pos, p.anchor = exprPos(attr.ValueExpr), p.value
+		} else if decl != nil && decl.Basic != "" {
+			value, ok := literalValue(decl.Basic, attr.ValueLiteral)
+			if !ok {
+				errorf(attr.Pos(), "cannot use %q as the %s value of prop %s of component %s", attr.ValueLiteral, decl.Type, name, r.Name)
+				continue
+			}
+			p.value = value
+		}
+		props = append(props, p)
+	}
+
+	if r.Pkg == "" {
+		for _, p := range r.Props {
+			if !set[p.Name] {
+				errorf(el.Name.Pos(), "missing prop %s of component %s", p.Name, r.Name)
+			}
+		}
+	}
+
+	return props
+}
+
+// literalValue returns the Go constant of the value lit of a
+// literal attribute converted to the type basic, one of
+// basicTypes, reporting whether lit is a valid value of it.
+// The attribute of a bare boolean attribute is "true".
+func literalValue(basic string, lit string) (string, bool) {
+	bits := basicTypes[basic]
+	switch basic {
+	case "string":
+		return strconv.Quote(lit), true
+	case "bool":
+		b, err := strconv.ParseBool(lit)
+		return strconv.FormatBool(b), err == nil
+	case "float32", "float64":
+		f, err := strconv.ParseFloat(lit, bits)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'g', -1, bits), true
+	}
+	if strings.HasPrefix(basic, "u") || basic == "byte" {
+		n, err := strconv.ParseUint(lit, 0, bits)
+		return strconv.FormatUint(n, 10), err == nil
+	}
+	n, err := strconv.ParseInt(lit, 0, bits)
+	return strconv.FormatInt(n, 10), err == nil
+}
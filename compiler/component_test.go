@@ -0,0 +1,76 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/ast"
+)
+
+func analyze(t *testing.T, code string) (*component, error) {
+	t.Helper()
+	cb, err := parseCodeBlock("main", &ast.CodeBlock{Code: code})
+	require.NoError(t, err)
+	return analyzeComponent("Mino", cb)
+}
+
+func TestAnalyzeComponent(t *testing.T) {
+	t.Run("props", func(t *testing.T) {
+		comp, err := analyze(t, "type Mino struct {\n\tMeep string `prop`\n\tizu, mino []int `json:\"-\" prop:\"\"`\n\ttext string\n}")
+		require.NoError(t, err)
+		assert.True(t, comp.Declared)
+		assert.Equal(t, []prop{
+			{Field: "Meep", Name: "Meep", Type: "string", TypePos: 39, Basic: "string"},
+			{Field: "izu", Name: "Izu", Type: "[]int", TypePos: 64},
+			{Field: "mino", Name: "Mino", Type: "[]int", TypePos: 64},
+		}, comp.Props)
+	})
+
+	t.Run("basic types", func(t *testing.T) {
+		comp, err := analyze(t, "type level uint8\ntype (alias = level)\ntype Mino struct {\n\ton bool `prop`\n\tl alias `prop`\n\tf *float64 `prop`\n\ts fmt.Stringer `prop`\n}")
+		require.NoError(t, err)
+		basics := make([]string, 0)
+		for _, p := range comp.Props {
+			basics = append(basics, p.Basic)
+		}
+		assert.Equal(t, []string{"bool", "uint8", "", ""}, basics)
+	})
+
+	t.Run("generated struct", func(t *testing.T) {
+		comp, err := analyze(t, "var izu = 1")
+		require.NoError(t, err)
+		assert.False(t, comp.Declared)
+		assert.Empty(t, comp.Props)
+	})
+
+	t.Run("clashing props", func(t *testing.T) {
+		_, err := analyze(t, "type Mino struct {\n\tMeep string `prop`\n\tmeep string `prop`\n}")
+		assert.ErrorContains(t, err, "prop meep of component Mino clashes with prop Meep")
+	})
+
+	t.Run("generated name declared", func(t *testing.T) {
+		_, err := analyze(t, "type MinoProps struct{}")
+		assert.ErrorContains(t, err, "MinoProps is generated")
+	})
+
+	t.Run("component not a struct", func(t *testing.T) {
+		_, err := analyze(t, "func Mino() {}")
+		assert.ErrorContains(t, err, "must be declared as a struct type")
+	})
+
+	t.Run("non-copyable prop", func(t *testing.T) {
+		_, err := analyze(t, "import \"sync\"\ntype Mino struct {\n\tmu sync.Mutex `prop`\n}")
+		assert.ErrorContains(t, err, "prop mu of component Mino: type sync.Mutex cannot be copied")
+	})
+
+	t.Run("non-copyable nested prop", func(t *testing.T) {
+		_, err := analyze(t, "import a \"sync/atomic\"\ntype state struct{ n [2]a.Int32 }\ntype Mino struct {\n\ts state `prop`\n\tp *state `prop`\n}")
+		assert.ErrorContains(t, err, "type state cannot be copied as it contains a.Int32")
+	})
+
+	t.Run("pointer to non-copyable", func(t *testing.T) {
+		_, err := analyze(t, "import \"sync\"\ntype Mino struct {\n\tmu *sync.Mutex `prop`\n}")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,35 @@
+package compiler
+
+import (
+	source "go/token"
+	"io"
+	"testing"
+
+	"github.com/tifye/flamingo/parser"
+)
+
+func FuzzCompileFile(f *testing.F) {
+	seeds := []string{
+		`<div class="bg-rose-500">mino</div>`,
+		"---\npackage meep\n\ntype Mino struct {\n\tMeep string `prop`\n}\n---\n<div>{c.Meep}</div>",
+		`<button on:click={c.handleClick}>{c.count}</button>`,
+		`<my-element data-x="a` + "`" + `b"/>`,
+		`<div>{#if c.a}<p/>{:else if c.b}b{:else}{c.c}{/if}</div>`,
+		`<ul>{#each c.items as item, i (item.ID)}<li>{item}</li>{:else}none{/each}</ul>`,
+		"---\ntype Mino int\n---\n",
+		"---\nimport \"example.com/ui\"\n---\n<div><ui.Button text=\"ok\"/>{#if c.a}<Mino/>{/if}</div>",
+		`<div><slot/><slot name="footer"><p>none</p></slot><Mino><p slot="footer">a</p>b</Mino></div>`,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		fset := source.NewFileSet()
+		root, err := parser.ParseFile(fset, "fuzz.flamingo", input)
+		if err != nil {
+			return
+		}
+		_ = CompileFile("main", fset, "Mino", root, io.Discard)
+	})
+}
@@ -0,0 +1,208 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"go/scanner"
+	source "go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// lineMarker prefixes the placeholders of line directives in
+// generated code. Lacking a colon they are not line directives
+// themselves, so they do not affect the parsing of the code.
+const lineMarker = "//line flamingo#"
+
+// lineDirectives maps the lines of generated code to the positions
+// in the template they are generated from, so that the Go toolchain
+// reports errors, panics and stack traces at the template. As their
+// columns depend on the layout of the formatted code, directives are
+// written as placeholders which are resolved once it is formatted.
+//
+// The positions are those of the file named in fset, which is
+// assumed to be in the same directory as the generated code, named
+// generated. Lines generated from a node of the template are mapped
+// back to the generated code once past the line generated from it.
+type lineDirectives struct {
+	fset       *source.FileSet
+	generated  string
+	directives []lineDirective
+}
+
+type lineDirective struct {
+	pos source.Position
+	// anchor is the code on the following line generated from pos,
+	// or empty if that is the code following the indentation
+	anchor string
+	// copied is set if the code from the following line on is
+	// copied from the template, its lines following that of pos
+	copied bool
+	// lineOnly is set if the anchor is split from the following
+	// line, see split
+	lineOnly bool
+}
+
+// mark returns the placeholder line of the directive mapping the
+// line following it to pos, from which the code anchor on that line
+// is generated, or if anchor is empty the code following the line's
+// indentation. It returns an empty string if pos is unknown.
+func (l *lineDirectives) mark(pos source.Pos, anchor string) string {
+	anchor, _, _ = strings.Cut(strings.TrimSpace(anchor), "\n")
+	return l.add(pos, lineDirective{anchor: strings.TrimSpace(anchor)})
+}
+
+// markCopied is like mark with an empty anchor, for code copied
+// from the template at pos, mapping the lines following the next
+// to those following pos.
+func (l *lineDirectives) markCopied(pos source.Pos) string {
+	return l.add(pos, lineDirective{copied: true})
+}
+
+func (l *lineDirectives) add(pos source.Pos, d lineDirective) string {
+	if l.fset == nil || !pos.IsValid() {
+		return ""
+	}
+	d.pos = l.fset.Position(pos)
+	if d.pos.Filename == "" || strings.ContainsAny(d.pos.Filename, "\r\n") {
+		return ""
+	}
+
+	l.directives = append(l.directives, d)
+	return fmt.Sprintf("%s%d\n", lineMarker, len(l.directives)-1)
+}
+
+// split moves each anchor further into its line than the column of
+// its position onto a line of its own, preceded by its directive,
+// so that its column can be set once the code is formatted again.
+// The line it is split from is mapped to the line of the position
+// alone. Anchors after a token at which a line break would end the
+// statement are left in place, the columns of their line unknown.
+func (l *lineDirectives) split(src []byte) []byte {
+	lines := bytes.Split(src, []byte("\n"))
+	inside := continuedLines(src)
+	out := make([][]byte, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		out = append(out, line)
+		d, ok := l.placeholder(line)
+		if !ok || inside[i] || d.anchor == "" || i+1 == len(lines) {
+			continue
+		}
+
+		next := lines[i+1]
+		at := bytes.LastIndex(next, []byte(d.anchor))
+		head := bytes.TrimRight(next[:max(at, 0)], " \t")
+		if at < 0 || d.pos.Column > at || len(bytes.TrimLeft(head, " \t")) == 0 || endsStatement(head) {
+			continue
+		}
+		l.directives = append(l.directives, lineDirective{pos: d.pos, lineOnly: true})
+		out[len(out)-1] = fmt.Appendf(nil, "%s%d", lineMarker, len(l.directives)-1)
+		out = append(out, head, line, next[at:])
+		i++
+	}
+	return bytes.Join(out, []byte("\n"))
+}
+
+// endsStatement reports whether a line break following the
+// code would end the statement.
+func endsStatement(code []byte) bool {
+	var s scanner.Scanner
+	file := source.NewFileSet().AddFile("", -1, len(code)+1)
+	s.Init(file, append(slices.Clip(code), '\n'), nil, 0)
+	for {
+		_, tok, lit := s.Scan()
+		switch {
+		case tok == source.EOF:
+			return false
+		case tok == source.SEMICOLON && lit == "\n":
+			return true
+		}
+	}
+}
+
+// continuedLines reports, by index, the lines of src which continue
+// a string or comment started on a previous line, where a directive
+// would change the value or be no directive.
+func continuedLines(src []byte) map[int]bool {
+	var s scanner.Scanner
+	file := source.NewFileSet().AddFile("", -1, len(src))
+	s.Init(file, src, nil, scanner.ScanComments)
+	inside := make(map[int]bool)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == source.EOF {
+			return inside
+		}
+		if tok != source.STRING && tok != source.COMMENT {
+			continue
+		}
+		start := file.Line(pos)
+		end := start + strings.Count(lit, "\n")
+		for line := start + 1; line <= end; line++ {
+			inside[line-1] = true
+		}
+	}
+}
+
+// resolve replaces the placeholders in src with line directives.
+// The column of a directive is set so the anchor on the following
+// line is at the column of its position, which split makes room
+// for. Otherwise the columns of the line are left unknown.
+// No directive is written between the lines of a string or
+// comment spanning several, as it would change the string.
+func (l *lineDirectives) resolve(src []byte) []byte {
+	if len(l.directives) == 0 {
+		return src
+	}
+
+	lines := bytes.Split(src, []byte("\n"))
+	inside := continuedLines(src)
+	out := make([][]byte, 0, len(lines))
+	reset := -1 // the line mapped back to the generated code
+	for i, line := range lines {
+		d, ok := l.placeholder(line)
+		if !ok || inside[i] {
+			if i >= reset && reset >= 0 && !inside[i] && l.generated != "" {
+				// The directive applies to the next output line
+				out = append(out, fmt.Appendf(nil, "//line %s:%d", l.generated, len(out)+2))
+				reset = -1
+			}
+			out = append(out, line)
+			continue
+		}
+
+		directive := fmt.Sprintf("//line %s:%d", filepath.Base(d.pos.Filename), d.pos.Line)
+		if i+1 < len(lines) && !d.lineOnly {
+			next := lines[i+1]
+			at := len(next) - len(bytes.TrimLeft(next, " \t"))
+			if d.anchor != "" {
+				at = bytes.LastIndex(next, []byte(d.anchor))
+			}
+			if at >= 0 && d.pos.Column > at {
+				directive += fmt.Sprintf(":%d", d.pos.Column-at)
+			}
+		}
+		out = append(out, []byte(directive))
+		reset = -1
+		if !d.copied {
+			reset = i + 2
+		}
+	}
+	return bytes.Join(out, []byte("\n"))
+}
+
+// placeholder returns the directive of the placeholder line.
+func (l *lineDirectives) placeholder(line []byte) (lineDirective, bool) {
+	rest, ok := bytes.CutPrefix(line, []byte(lineMarker))
+	if !ok {
+		return lineDirective{}, false
+	}
+	n, err := strconv.Atoi(string(rest))
+	if err != nil || n < 0 || n >= len(l.directives) {
+		return lineDirective{}, false
+	}
+	return l.directives[n], true
+}
@@ -0,0 +1,61 @@
+package compiler
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// A Sink receives the files generated by CompileDir, named
+// relative to the compiled directory.
+type Sink interface {
+	// WriteFile writes the generated file name, replacing it if it
+	// exists. It must not leave the file partially written on error.
+	WriteFile(name string, data []byte) error
+	// Remove removes the generated file name, whose template
+	// no longer exists.
+	Remove(name string) error
+}
+
+// DirSink is a Sink writing the generated files to a directory.
+type DirSink struct {
+	dir string
+}
+
+// NewDirSink returns a Sink writing the generated files to dir.
+func NewDirSink(dir string) *DirSink {
+	return &DirSink{dir: dir}
+}
+
+// WriteFile writes the generated file name to a temporary file in
+// the directory, which is renamed to name once fully written.
+func (d *DirSink) WriteFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(d.dir, "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	// Removing the temporary file fails once it is renamed
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(d.dir, name))
+}
+
+// Remove removes the generated file name, if it exists.
+func (d *DirSink) Remove(name string) error {
+	err := os.Remove(filepath.Join(d.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
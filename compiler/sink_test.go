@@ -0,0 +1,35 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirSink(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewDirSink(dir)
+
+	require.NoError(t, sink.WriteFile("Mino_flamingo.go", []byte("package meep\n")))
+	require.NoError(t, sink.WriteFile("Mino_flamingo.go", []byte("package mino\n")))
+
+	data, err := os.ReadFile(filepath.Join(dir, "Mino_flamingo.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package mino\n", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "expected no temporary files to be left")
+	info, err := entries[0].Info()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	require.NoError(t, sink.Remove("Mino_flamingo.go"))
+	assert.NoFileExists(t, filepath.Join(dir, "Mino_flamingo.go"))
+	assert.NoError(t, sink.Remove("Mino_flamingo.go"), "expected removing a missing file to succeed")
+
+	assert.Error(t, NewDirSink(filepath.Join(dir, "missing")).WriteFile("Mino_flamingo.go", nil))
+}
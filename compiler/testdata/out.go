@@ -34,10 +34,10 @@ func MinoComp(renderer render.Renderer, props MinoProps) {
 		Meep: props.Meep,
 	}
 
-	// ... or other lifecycle stuff
-	if mounter, ok := comp.(Mounter); ok {
-		mounter.OnMount()
-	}
+	// ... build the component tree
 
-	// ...
+	// the renderer invokes render.Mounter and friends
+	// on comp as its root is mounted, updated and removed
+	renderer.Attach(div1, comp)
+	renderer.Render(div1)
 }
@@ -0,0 +1,218 @@
+// Code generated by flamingo. DO NOT EDIT.
+
+package e2e
+
+import (
+	"fmt"
+
+	"github.com/tifye/flamingo/render"
+)
+
+//line App.flamingo:4:1
+type App struct {
+	render.Reactive
+//line App.flamingo:5:1
+	items []string
+	level int
+}
+
+//line App.flamingo:9:1
+func (c *App) more() {
+	c.items = append(c.items, fmt.Sprint("item ", len(c.items)+1))
+	c.level++
+	c.Invalidate("items", "level")
+}
+
+//line App.flamingo:15:1
+type AppProps struct {
+//line App_flamingo.go:29
+}
+
+//line App.flamingo:15:1
+func AppComp(renderer render.Renderer, props AppProps) {
+//line App_flamingo.go:34
+	renderer.Render(AppTree(renderer, props, nil)...)
+}
+
+func AppTree(renderer render.Renderer, props AppProps, slots render.Slots) []render.Component {
+	c := &App{
+		Reactive: render.NewReactive(renderer),
+	}
+	roots := make([]render.Component, 0)
+
+//line App.flamingo:15
+	main1 := renderer.NewComponent("main")
+//line App_flamingo.go:46
+	roots = append(roots, main1)
+
+//line App.flamingo:16:1
+	Counter2 := CounterTree(renderer, CounterProps{
+//line App.flamingo:16:9
+		Start: 1,
+//line App.flamingo:16:19
+		Label: "first",
+//line App.flamingo:16:33
+		Done: true,
+//line App_flamingo.go:57
+	}, nil)
+	for _, child := range Counter2 {
+		renderer.Append(main1, child)
+	}
+
+//line App.flamingo:16:40
+	text3 := renderer.NewText(` `)
+//line App_flamingo.go:65
+	renderer.Append(main1, text3)
+
+//line App.flamingo:17:1
+	Counter4 := CounterTree(renderer, CounterProps{
+//line App.flamingo:17:9
+		Start: 10,
+//line App.flamingo:17:20
+		Label: "second",
+//line App.flamingo:17:37
+		Done: false,
+//line App_flamingo.go:76
+	}, nil)
+	for _, child := range Counter4 {
+		renderer.Append(main1, child)
+	}
+
+//line App.flamingo:17:52
+	text5 := renderer.NewText(` `)
+//line App_flamingo.go:84
+	renderer.Append(main1, text5)
+
+//line App.flamingo:18:1
+	Card6 := CardTree(renderer, CardProps{
+//line App.flamingo:18:6
+		Title: "Items",
+//line App_flamingo.go:91
+	}, render.Slots{
+		"": func(parent render.Component) {
+//line App.flamingo:19
+			ol7 := renderer.NewComponent("ol")
+//line App_flamingo.go:96
+			renderer.Append(parent, ol7)
+
+//line App.flamingo:20:1
+			c.Block(ol7, []string{"items"}, func(parent render.Component) {
+//line App.flamingo:20
+				for i, item := range
+//line App.flamingo:20:7
+				c.items {
+//line App.flamingo:21
+					li8 := renderer.NewComponent("li")
+//line App.flamingo:21
+					li8.SetAttribute("data-index",
+//line App.flamingo:21:15
+						i)
+//line App_flamingo.go:111
+					renderer.Append(parent, li8)
+
+//line App.flamingo:21:19
+					text9 := renderer.NewText("")
+//line App.flamingo:21
+					text9.SetAttribute("text",
+//line App.flamingo:21:19
+						item)
+//line App_flamingo.go:120
+					renderer.Append(li8, text9)
+				}
+			})
+		},
+		"footer": func(parent render.Component) {
+//line App.flamingo:24
+			p10 := renderer.NewComponent("p")
+//line App_flamingo.go:128
+			renderer.Append(parent, p10)
+
+//line App.flamingo:24:17
+			text11 := renderer.NewText("")
+//line App_flamingo.go:133
+			c.Bind([]string{"items"}, func() {
+//line App.flamingo:24
+				text11.SetAttribute("text",
+//line App.flamingo:24:16
+					len(c.items))
+//line App_flamingo.go:139
+			})
+			renderer.Append(p10, text11)
+
+//line App.flamingo:24:31
+			text12 := renderer.NewText(` items`)
+//line App_flamingo.go:145
+			renderer.Append(p10, text12)
+		},
+	})
+	for _, child := range Card6 {
+		renderer.Append(main1, child)
+	}
+
+//line App.flamingo:25:8
+	text13 := renderer.NewText(` `)
+//line App_flamingo.go:155
+	renderer.Append(main1, text13)
+
+//line App.flamingo:26:1
+	Card14 := CardTree(renderer, CardProps{
+//line App.flamingo:26:6
+		Title: "Empty",
+//line App_flamingo.go:162
+	}, nil)
+	for _, child := range Card14 {
+		renderer.Append(main1, child)
+	}
+
+//line App.flamingo:27:1
+	p15 := renderer.NewComponent("p")
+//line App_flamingo.go:170
+	p15.SetAttribute("id", "level")
+	renderer.Append(main1, p15)
+
+//line App.flamingo:27:15
+	c.Block(p15, []string{"level"}, func(parent render.Component) {
+//line App.flamingo:27:16
+		if c.level > 1 {
+//line App.flamingo:27:30
+			text16 := renderer.NewText(`high`)
+//line App_flamingo.go:180
+			renderer.Append(parent, text16)
+//line App.flamingo:27:35
+		} else if c.level == 1 {
+//line App.flamingo:27:57
+			text17 := renderer.NewText(`low`)
+//line App_flamingo.go:186
+			renderer.Append(parent, text17)
+		} else {
+//line App.flamingo:27:67
+			text18 := renderer.NewText(`none`)
+//line App_flamingo.go:191
+			renderer.Append(parent, text18)
+		}
+	})
+
+//line App.flamingo:28:1
+	button19 := renderer.NewComponent("button")
+//line App_flamingo.go:198
+	button19.SetAttribute("id", "more")
+//line App.flamingo:28
+	button19.SetAttribute("on:click",
+//line App.flamingo:28:28
+		c.more)
+//line App_flamingo.go:204
+	renderer.Append(main1, button19)
+
+//line App.flamingo:28:37
+	text20 := renderer.NewText(`More`)
+//line App_flamingo.go:209
+	renderer.Append(button19, text20)
+
+//line App.flamingo:15
+	if len(roots) > 0 {
+//line App_flamingo.go:214
+		renderer.Attach(roots[0], c)
+		c.SetRoot(roots[0])
+	}
+	return roots
+}
@@ -0,0 +1,92 @@
+// Code generated by flamingo. DO NOT EDIT.
+
+package e2e
+
+import (
+	"github.com/tifye/flamingo/render"
+)
+
+//line Card.flamingo:2:1
+type Card struct {
+	render.Reactive
+//line Card.flamingo:3:1
+	Title string `prop:""`
+}
+
+//line Card.flamingo:6:1
+type CardProps struct {
+//line Card.flamingo:3:1
+	Title string
+//line Card_flamingo.go:21
+}
+
+//line Card.flamingo:6:1
+func CardComp(renderer render.Renderer, props CardProps) {
+//line Card_flamingo.go:26
+	renderer.Render(CardTree(renderer, props, nil)...)
+}
+
+func CardTree(renderer render.Renderer, props CardProps, slots render.Slots) []render.Component {
+	c := &Card{
+		Reactive: render.NewReactive(renderer),
+		Title:    props.Title,
+	}
+	roots := make([]render.Component, 0)
+
+//line Card.flamingo:6
+	article1 := renderer.NewComponent("article")
+//line Card_flamingo.go:39
+	roots = append(roots, article1)
+
+//line Card.flamingo:7:1
+	h22 := renderer.NewComponent("h2")
+//line Card_flamingo.go:44
+	renderer.Append(article1, h22)
+
+//line Card.flamingo:7:5
+	text3 := renderer.NewText("")
+//line Card_flamingo.go:49
+	c.Bind([]string{"Title"}, func() {
+//line Card.flamingo:7
+		text3.SetAttribute("text",
+//line Card.flamingo:7:4
+			c.Title)
+//line Card_flamingo.go:55
+	})
+	renderer.Append(h22, text3)
+
+//line Card.flamingo:8:1
+	if slot := slots[""]; slot != nil {
+//line Card_flamingo.go:61
+		slot(article1)
+	}
+
+//line Card.flamingo:9:1
+	footer4 := renderer.NewComponent("footer")
+//line Card_flamingo.go:67
+	renderer.Append(article1, footer4)
+
+//line Card.flamingo:9:9
+	if slot := slots["footer"]; slot != nil {
+//line Card_flamingo.go:72
+		slot(footer4)
+	} else {
+//line Card.flamingo:9:28
+		p5 := renderer.NewComponent("p")
+//line Card_flamingo.go:77
+		renderer.Append(footer4, p5)
+
+//line Card.flamingo:9:31
+		text6 := renderer.NewText(`No footer`)
+//line Card_flamingo.go:82
+		renderer.Append(p5, text6)
+	}
+
+//line Card.flamingo:6
+	if len(roots) > 0 {
+//line Card_flamingo.go:88
+		renderer.Attach(roots[0], c)
+		c.SetRoot(roots[0])
+	}
+	return roots
+}
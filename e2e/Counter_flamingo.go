@@ -0,0 +1,98 @@
+// Code generated by flamingo. DO NOT EDIT.
+
+package e2e
+
+import (
+	"github.com/tifye/flamingo/render"
+)
+
+//line Counter.flamingo:2:1
+type Counter struct {
+	render.Reactive
+//line Counter.flamingo:3:1
+	Start int    `prop:""`
+	Label string `prop:""`
+	Done  bool   `prop:""`
+	count int
+}
+
+//line Counter.flamingo:9:1
+func (c *Counter) increment() {
+	c.count++
+	c.Invalidate("count")
+}
+
+//line Counter.flamingo:14:1
+type CounterProps struct {
+//line Counter.flamingo:3:1
+	Start int
+//line Counter.flamingo:4:1
+	Label string
+//line Counter.flamingo:5:2
+	Done bool
+//line Counter_flamingo.go:34
+}
+
+//line Counter.flamingo:14:1
+func CounterComp(renderer render.Renderer, props CounterProps) {
+//line Counter_flamingo.go:39
+	renderer.Render(CounterTree(renderer, props, nil)...)
+}
+
+func CounterTree(renderer render.Renderer, props CounterProps, slots render.Slots) []render.Component {
+	c := &Counter{
+		Reactive: render.NewReactive(renderer),
+		Start:    props.Start,
+		Label:    props.Label,
+		Done:     props.Done,
+	}
+	roots := make([]render.Component, 0)
+
+//line Counter.flamingo:14
+	button1 := renderer.NewComponent("button")
+//line Counter_flamingo.go:54
+	c.Bind([]string{"Done"}, func() {
+//line Counter.flamingo:14
+		button1.SetAttribute("data-done",
+//line Counter.flamingo:14:17
+			c.Done)
+//line Counter_flamingo.go:60
+	})
+//line Counter.flamingo:14:4
+	button1.SetAttribute("on:click", c.increment)
+//line Counter_flamingo.go:64
+	roots = append(roots, button1)
+
+//line Counter.flamingo:14:50
+	text2 := renderer.NewText("")
+//line Counter_flamingo.go:69
+	c.Bind([]string{"Label"}, func() {
+//line Counter.flamingo:14:23
+		text2.SetAttribute("text", c.Label)
+//line Counter_flamingo.go:73
+	})
+	renderer.Append(button1, text2)
+
+//line Counter.flamingo:14:59
+	text3 := renderer.NewText(`: `)
+//line Counter_flamingo.go:79
+	renderer.Append(button1, text3)
+
+//line Counter.flamingo:14:61
+	text4 := renderer.NewText("")
+//line Counter_flamingo.go:84
+	c.Bind([]string{"Start", "count"}, func() {
+//line Counter.flamingo:14
+		text4.SetAttribute("text", c.Start+c.count)
+//line Counter_flamingo.go:88
+	})
+	renderer.Append(button1, text4)
+
+//line Counter.flamingo:14
+	if len(roots) > 0 {
+//line Counter_flamingo.go:94
+		renderer.Attach(roots[0], c)
+		c.SetRoot(roots[0])
+	}
+	return roots
+}
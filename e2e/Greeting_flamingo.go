@@ -0,0 +1,145 @@
+// Code generated by flamingo. DO NOT EDIT.
+
+package e2e
+
+import (
+	"github.com/tifye/flamingo/render"
+)
+
+//line Greeting.flamingo:2:1
+type Greeting struct {
+	render.Reactive
+//line Greeting.flamingo:3:1
+	Name string `prop:""`
+}
+
+//line Greeting.flamingo:6:1
+type GreetingProps struct {
+//line Greeting.flamingo:3:1
+	Name string
+//line Greeting_flamingo.go:21
+}
+
+//line Greeting.flamingo:6:1
+func GreetingComp(renderer render.Renderer, props GreetingProps) {
+//line Greeting_flamingo.go:26
+	renderer.Render(GreetingTree(renderer, props, nil)...)
+}
+
+func GreetingTree(renderer render.Renderer, props GreetingProps, slots render.Slots) []render.Component {
+	c := &Greeting{
+		Reactive: render.NewReactive(renderer),
+		Name:     props.Name,
+	}
+	roots := make([]render.Component, 0)
+
+//line Greeting.flamingo:6
+	p1 := renderer.NewComponent("p")
+//line Greeting_flamingo.go:39
+	roots = append(roots, p1)
+
+//line Greeting.flamingo:6:3
+	text2 := renderer.NewText(`Hello `)
+//line Greeting_flamingo.go:44
+	renderer.Append(p1, text2)
+
+//line Greeting.flamingo:6:9
+	b3 := renderer.NewComponent("b")
+//line Greeting_flamingo.go:49
+	renderer.Append(p1, b3)
+
+//line Greeting.flamingo:6:12
+	text4 := renderer.NewText(`world`)
+//line Greeting_flamingo.go:54
+	renderer.Append(b3, text4)
+
+//line Greeting.flamingo:6:21
+	text5 := renderer.NewText(` again, `)
+//line Greeting_flamingo.go:59
+	renderer.Append(p1, text5)
+
+//line Greeting.flamingo:6:29
+	text6 := renderer.NewText("")
+//line Greeting_flamingo.go:64
+	c.Bind([]string{"Name"}, func() {
+//line Greeting.flamingo:6:2
+		text6.SetAttribute("text", c.Name)
+//line Greeting_flamingo.go:68
+	})
+	renderer.Append(p1, text6)
+
+//line Greeting.flamingo:6:37
+	text7 := renderer.NewText(`!`)
+//line Greeting_flamingo.go:74
+	renderer.Append(p1, text7)
+
+//line Greeting.flamingo:7
+	ul8 := renderer.NewComponent("ul")
+//line Greeting_flamingo.go:79
+	roots = append(roots, ul8)
+
+//line Greeting.flamingo:8:1
+	li9 := renderer.NewComponent("li")
+//line Greeting_flamingo.go:84
+	renderer.Append(ul8, li9)
+
+//line Greeting.flamingo:8:5
+	text10 := renderer.NewText(` first item `)
+//line Greeting_flamingo.go:89
+	renderer.Append(li9, text10)
+
+//line Greeting.flamingo:12:1
+	li11 := renderer.NewComponent("li")
+//line Greeting_flamingo.go:94
+	renderer.Append(ul8, li11)
+
+//line Greeting.flamingo:13:2
+	text12 := renderer.NewText("")
+//line Greeting_flamingo.go:99
+	c.Bind([]string{"Name"}, func() {
+//line Greeting.flamingo:13
+		text12.SetAttribute("text",
+//line Greeting.flamingo:13:1
+			c.Name)
+//line Greeting_flamingo.go:105
+	})
+	renderer.Append(li11, text12)
+
+//line Greeting.flamingo:13:10
+	text13 := renderer.NewText(` `)
+//line Greeting_flamingo.go:111
+	renderer.Append(li11, text13)
+
+//line Greeting.flamingo:14:2
+	i14 := renderer.NewComponent("i")
+//line Greeting_flamingo.go:116
+	renderer.Append(li11, i14)
+
+//line Greeting.flamingo:14:5
+	text15 := renderer.NewText(`second`)
+//line Greeting_flamingo.go:121
+	renderer.Append(i14, text15)
+
+//line Greeting.flamingo:14:15
+	text16 := renderer.NewText(` `)
+//line Greeting_flamingo.go:126
+	renderer.Append(li11, text16)
+
+//line Greeting.flamingo:15:2
+	b17 := renderer.NewComponent("b")
+//line Greeting_flamingo.go:131
+	renderer.Append(li11, b17)
+
+//line Greeting.flamingo:15:5
+	text18 := renderer.NewText(`item`)
+//line Greeting_flamingo.go:136
+	renderer.Append(b17, text18)
+
+//line Greeting.flamingo:6
+	if len(roots) > 0 {
+//line Greeting_flamingo.go:141
+		renderer.Attach(roots[0], c)
+		c.SetRoot(roots[0])
+	}
+	return roots
+}
@@ -0,0 +1,229 @@
+// Code generated by flamingo. DO NOT EDIT.
+
+package e2e
+
+import (
+	"fmt"
+
+	"github.com/tifye/flamingo/render"
+)
+
+//line Todos.flamingo:4:1
+type Todo struct {
+	ID   int
+	Text string
+}
+
+//line Todos.flamingo:9:1
+type Todos struct {
+	render.Reactive
+//line Todos.flamingo:10:1
+	todos    []Todo
+	loading  bool
+	selected int
+	Label    string `prop:""`
+}
+
+//line Todos.flamingo:16:1
+func (c *Todos) add() {
+	id := len(c.todos) + 1
+	c.todos = append([]Todo{{ID: id, Text: fmt.Sprint(c.Label, " ", id)}}, c.todos...)
+	c.Invalidate("todos")
+}
+
+//line Todos.flamingo:22:1
+func (c *Todos) selectFirst() {
+	c.selected = 1
+	c.Invalidate("selected")
+}
+
+//line Todos.flamingo:27:1
+func (c *Todos) toggleLoading() {
+	c.loading = !c.loading
+	c.Invalidate("loading")
+}
+
+//line Todos.flamingo:32:1
+func (c *Todos) count() int {
+	return len(c.todos)
+}
+
+//line Todos.flamingo:36:1
+type TodosProps struct {
+//line Todos.flamingo:13:4
+	Label string
+//line Todos_flamingo.go:56
+}
+
+//line Todos.flamingo:36:1
+func TodosComp(renderer render.Renderer, props TodosProps) {
+//line Todos_flamingo.go:61
+	renderer.Render(TodosTree(renderer, props, nil)...)
+}
+
+func TodosTree(renderer render.Renderer, props TodosProps, slots render.Slots) []render.Component {
+	c := &Todos{
+		Reactive: render.NewReactive(renderer),
+		Label:    props.Label,
+	}
+	roots := make([]render.Component, 0)
+
+//line Todos.flamingo:36
+	section1 := renderer.NewComponent("section")
+//line Todos_flamingo.go:74
+	roots = append(roots, section1)
+
+//line Todos.flamingo:37:1
+	button2 := renderer.NewComponent("button")
+//line Todos_flamingo.go:79
+	button2.SetAttribute("id", "add")
+//line Todos.flamingo:37
+	button2.SetAttribute("on:click",
+//line Todos.flamingo:37:27
+		c.add)
+//line Todos_flamingo.go:85
+	renderer.Append(section1, button2)
+
+//line Todos.flamingo:37:35
+	text3 := renderer.NewText(`Add`)
+//line Todos_flamingo.go:90
+	renderer.Append(button2, text3)
+
+//line Todos.flamingo:37:47
+	text4 := renderer.NewText(` `)
+//line Todos_flamingo.go:95
+	renderer.Append(section1, text4)
+
+//line Todos.flamingo:38:1
+	button5 := renderer.NewComponent("button")
+//line Todos_flamingo.go:100
+	button5.SetAttribute("id", "select")
+//line Todos.flamingo:38
+	button5.SetAttribute("on:click",
+//line Todos.flamingo:38:30
+		c.selectFirst)
+//line Todos_flamingo.go:106
+	renderer.Append(section1, button5)
+
+//line Todos.flamingo:38:46
+	text6 := renderer.NewText(`Select`)
+//line Todos_flamingo.go:111
+	renderer.Append(button5, text6)
+
+//line Todos.flamingo:38:61
+	text7 := renderer.NewText(` `)
+//line Todos_flamingo.go:116
+	renderer.Append(section1, text7)
+
+//line Todos.flamingo:39:1
+	button8 := renderer.NewComponent("button")
+//line Todos_flamingo.go:121
+	button8.SetAttribute("id", "load")
+//line Todos.flamingo:39
+	button8.SetAttribute("on:click",
+//line Todos.flamingo:39:28
+		c.toggleLoading)
+//line Todos_flamingo.go:127
+	renderer.Append(section1, button8)
+
+//line Todos.flamingo:39:46
+	text9 := renderer.NewText(`Load`)
+//line Todos_flamingo.go:132
+	renderer.Append(button8, text9)
+
+//line Todos.flamingo:40:1
+	c.Block(section1, []string{"loading"}, func(parent render.Component) {
+//line Todos.flamingo:40:2
+		if c.loading {
+//line Todos.flamingo:41
+			p10 := renderer.NewComponent("p")
+//line Todos_flamingo.go:141
+			renderer.Append(parent, p10)
+
+//line Todos.flamingo:41:3
+			text11 := renderer.NewText(`Loading`)
+//line Todos_flamingo.go:146
+			renderer.Append(p10, text11)
+		} else {
+//line Todos.flamingo:43
+			ul12 := renderer.NewComponent("ul")
+//line Todos_flamingo.go:151
+			renderer.Append(parent, ul12)
+
+//line Todos.flamingo:44:1
+			c.Block(ul12, []string{"todos", "selected"}, func(parent render.Component) {
+//line Todos_flamingo.go:156
+				empty13 := true
+//line Todos.flamingo:44
+				for _, todo := range
+//line Todos.flamingo:44:7
+				c.todos {
+//line Todos_flamingo.go:162
+					empty13 = false
+
+//line Todos.flamingo:45
+					li14 := renderer.NewComponent("li")
+//line Todos.flamingo:44
+					li14.SetAttribute("key",
+//line Todos.flamingo:44:22
+						todo.ID)
+//line Todos.flamingo:45
+					li14.SetAttribute("data-selected",
+//line Todos.flamingo:45:18
+						todo.ID == c.selected)
+//line Todos_flamingo.go:175
+					renderer.Append(parent, li14)
+
+//line Todos.flamingo:45:42
+					text15 := renderer.NewText("")
+//line Todos.flamingo:45:15
+					text15.SetAttribute("text", todo.Text)
+//line Todos_flamingo.go:182
+					renderer.Append(li14, text15)
+				}
+				if empty13 {
+//line Todos.flamingo:47
+					li16 := renderer.NewComponent("li")
+//line Todos_flamingo.go:188
+					renderer.Append(parent, li16)
+
+//line Todos.flamingo:47:4
+					text17 := renderer.NewText(`Nothing to do`)
+//line Todos_flamingo.go:193
+					renderer.Append(li16, text17)
+				}
+			})
+		}
+	})
+
+//line Todos.flamingo:51:1
+	p18 := renderer.NewComponent("p")
+//line Todos_flamingo.go:202
+	p18.SetAttribute("id", "count")
+	renderer.Append(section1, p18)
+
+//line Todos.flamingo:51:15
+	text19 := renderer.NewText("")
+//line Todos_flamingo.go:208
+	c.Bind(nil, func() {
+//line Todos.flamingo:51
+		text19.SetAttribute("text",
+//line Todos.flamingo:51:14
+			c.count())
+//line Todos_flamingo.go:214
+	})
+	renderer.Append(p18, text19)
+
+//line Todos.flamingo:51:26
+	text20 := renderer.NewText(` todos`)
+//line Todos_flamingo.go:220
+	renderer.Append(p18, text20)
+
+//line Todos.flamingo:36
+	if len(roots) > 0 {
+//line Todos_flamingo.go:225
+		renderer.Attach(roots[0], c)
+		c.SetRoot(roots[0])
+	}
+	return roots
+}
@@ -0,0 +1,129 @@
+// Code generated by flamingo. DO NOT EDIT.
+
+package e2e
+
+import (
+	"fmt"
+
+	"github.com/tifye/flamingo/render"
+)
+
+//line Top.flamingo:4:1
+type Top struct {
+	render.Reactive
+//line Top.flamingo:5:1
+	loading bool
+	items   []string
+}
+
+//line Top.flamingo:9:1
+func (c *Top) toggleLoading() {
+	c.loading = !c.loading
+	c.Invalidate("loading")
+}
+
+//line Top.flamingo:14:1
+func (c *Top) add() {
+	c.items = append(c.items, fmt.Sprint("item ", len(c.items)+1))
+	c.Invalidate("items")
+}
+
+//line Top.flamingo:19:1
+type TopProps struct {
+//line Top_flamingo.go:34
+}
+
+//line Top.flamingo:19:1
+func TopComp(renderer render.Renderer, props TopProps) {
+//line Top_flamingo.go:39
+	renderer.Render(TopTree(renderer, props, nil)...)
+}
+
+func TopTree(renderer render.Renderer, props TopProps, slots render.Slots) []render.Component {
+	c := &Top{
+		Reactive: render.NewReactive(renderer),
+	}
+	roots := make([]render.Component, 0)
+
+//line Top.flamingo:19
+	button1 := renderer.NewComponent("button")
+//line Top_flamingo.go:51
+	button1.SetAttribute("id", "load")
+//line Top.flamingo:19
+	button1.SetAttribute("on:click",
+//line Top.flamingo:19:27
+		c.toggleLoading)
+//line Top_flamingo.go:57
+	roots = append(roots, button1)
+
+//line Top.flamingo:19:45
+	text2 := renderer.NewText(`Load`)
+//line Top_flamingo.go:62
+	renderer.Append(button1, text2)
+
+//line Top.flamingo:20
+	roots = append(roots, c.RootBlock([]string{"loading"}, func(parent render.Component) {
+//line Top.flamingo:20:1
+		if c.loading {
+//line Top.flamingo:21
+			p3 := renderer.NewComponent("p")
+//line Top_flamingo.go:71
+			renderer.Append(parent, p3)
+
+//line Top.flamingo:21:2
+			text4 := renderer.NewText(`Loading`)
+//line Top_flamingo.go:76
+			renderer.Append(p3, text4)
+		}
+	})...)
+
+//line Top.flamingo:23
+	roots = append(roots, c.RootBlock([]string{"items"}, func(parent render.Component) {
+//line Top.flamingo:23
+		for _, item := range
+//line Top.flamingo:23:6
+		c.items {
+//line Top.flamingo:24
+			p5 := renderer.NewComponent("p")
+//line Top.flamingo:23
+			p5.SetAttribute("key",
+//line Top.flamingo:23:21
+				item)
+//line Top_flamingo.go:93
+			renderer.Append(parent, p5)
+
+//line Top.flamingo:24:2
+			text6 := renderer.NewText("")
+//line Top.flamingo:24
+			text6.SetAttribute("text",
+//line Top.flamingo:24:2
+				item)
+//line Top_flamingo.go:102
+			renderer.Append(p5, text6)
+		}
+	})...)
+
+//line Top.flamingo:26
+	button7 := renderer.NewComponent("button")
+//line Top_flamingo.go:109
+	button7.SetAttribute("id", "add")
+//line Top.flamingo:26
+	button7.SetAttribute("on:click",
+//line Top.flamingo:26:26
+		c.add)
+//line Top_flamingo.go:115
+	roots = append(roots, button7)
+
+//line Top.flamingo:26:34
+	text8 := renderer.NewText(`Add`)
+//line Top_flamingo.go:120
+	renderer.Append(button7, text8)
+
+//line Top.flamingo:19
+	if len(roots) > 0 {
+//line Top_flamingo.go:125
+		renderer.Attach(roots[0], c)
+		c.SetRoot(roots[0])
+	}
+	return roots
+}
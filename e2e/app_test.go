@@ -0,0 +1,78 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/flamingotest"
+	"github.com/tifye/flamingo/html"
+)
+
+func TestComponents(t *testing.T) {
+	r := flamingotest.NewRenderer()
+	AppComp(r, AppProps{})
+
+	first := r.Query(flamingotest.ByText("first: 1"))
+	require.NotNil(t, first, "expected literal props to be converted to the prop types")
+	done, _ := first.Attribute("data-done")
+	assert.Equal(t, true, done, "expected a bare attribute to set a bool prop")
+	second := r.Query(flamingotest.ByText("second: 10"))
+	require.NotNil(t, second)
+
+	require.NoError(t, first.Click())
+	r.Flush()
+	assert.Equal(t, "first: 2", first.Text(), "expected the output of components to be updated")
+	assert.Equal(t, "second: 10", second.Text())
+}
+
+func TestSlots(t *testing.T) {
+	r := flamingotest.NewRenderer()
+	AppComp(r, AppProps{})
+
+	cards := r.QueryAll(flamingotest.ByTag("article"))
+	require.Len(t, cards, 2)
+	assert.Equal(t, "Items0 items", cards[0].Text(), "expected content to be passed to the default and named slots")
+	assert.NotNil(t, cards[0].Query(flamingotest.ByTag("ol")))
+	assert.Equal(t, "EmptyNo footer", cards[1].Text(), "expected the fallback of slots passed nothing")
+
+	require.NoError(t, r.Query(flamingotest.ByAttr("id", "more")).Click())
+	r.Flush()
+	assert.Equal(t, "Itemsitem 11 items", cards[0].Text(), "expected slot content to be bound to the component passing it")
+}
+
+func TestBlocks(t *testing.T) {
+	r := flamingotest.NewRenderer()
+	AppComp(r, AppProps{})
+	level := r.Query(flamingotest.ByAttr("id", "level"))
+	more := func() {
+		t.Helper()
+		require.NoError(t, r.Query(flamingotest.ByAttr("id", "more")).Click())
+		r.Flush()
+	}
+	assert.Equal(t, "none", level.Text())
+
+	more()
+	assert.Equal(t, "low", level.Text(), "expected the else if branch once its condition holds")
+	more()
+	assert.Equal(t, "high", level.Text())
+
+	items := r.QueryAll(flamingotest.ByTag("li"))
+	require.Len(t, items, 2)
+	for i, item := range items {
+		index, _ := item.Attribute("data-index")
+		assert.Equal(t, i, index)
+	}
+	assert.Equal(t, "item 1item 2", r.Query(flamingotest.ByTag("ol")).Text())
+}
+
+func TestRenderHTML(t *testing.T) {
+	r := html.NewRenderer()
+	AppComp(r, AppProps{})
+	assert.Equal(t, `<main>`+
+		`<button data-done="true">first: 1</button> <button data-done="false">second: 10</button> `+
+		`<article><h2>Items</h2><ol></ol><footer><p>0 items</p></footer></article> `+
+		`<article><h2>Empty</h2><footer><p>No footer</p></footer></article>`+
+		`<p id="level">none</p><button id="more">More</button>`+
+		`</main>`, r.String())
+}
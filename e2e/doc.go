@@ -0,0 +1,6 @@
+// Package e2e holds components compiled by flamingo, whose tests
+// render them to check the behaviour of the generated code. The
+// compiler's tests check that the generated code is up to date.
+package e2e
+
+//go:generate go run ..
@@ -0,0 +1,20 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tifye/flamingo/flamingotest"
+	"github.com/tifye/flamingo/html"
+)
+
+func TestTextWhiteSpace(t *testing.T) {
+	r := html.NewRenderer()
+	GreetingComp(r, GreetingProps{Name: "Bob"})
+	assert.Equal(t, "<p>Hello <b>world</b> again, Bob!</p><ul><li> first item </li><li>Bob <i>second</i> <b>item</b></li></ul>", r.String(), "expected white space breaking lines to be dropped around block-level elements only")
+
+	tr := flamingotest.NewRenderer()
+	GreetingComp(tr, GreetingProps{Name: "Bob"})
+	assert.Equal(t, "Hello world again, Bob! first item Bob second item", tr.Text())
+	assert.NotNil(t, tr.Query(flamingotest.ByText("Hello again, Bob!")))
+}
@@ -0,0 +1,31 @@
+package e2e
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tifye/flamingo/html"
+)
+
+func TestRenderParallel(t *testing.T) {
+	// Run with -race to check that renderers
+	// used by different goroutines share no state
+	var wg sync.WaitGroup
+	outputs := make([]string, 50)
+	for i := range outputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := html.NewRenderer()
+			TodosComp(r, TodosProps{Label: "todo"})
+			GreetingComp(r, GreetingProps{Name: "Bob"})
+			outputs[i] = r.String()
+		}()
+	}
+	wg.Wait()
+
+	for _, out := range outputs {
+		assert.Equal(t, outputs[0], out)
+	}
+}
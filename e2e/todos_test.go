@@ -0,0 +1,62 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/flamingotest"
+	"github.com/tifye/flamingo/html"
+)
+
+func TestBlocksUpdate(t *testing.T) {
+	r := flamingotest.NewRenderer()
+	TodosComp(r, TodosProps{Label: "todo"})
+	click := func(id string) {
+		t.Helper()
+		require.NoError(t, r.Query(flamingotest.ByAttr("id", id)).Click())
+		r.Flush()
+	}
+	items := func() string {
+		t.Helper()
+		ul := r.Query(flamingotest.ByTag("ul"))
+		require.NotNil(t, ul)
+		return ul.Text()
+	}
+	count := func() string {
+		return r.Query(flamingotest.ByAttr("id", "count")).Text()
+	}
+	assert.Equal(t, "Nothing to do", items())
+	assert.Equal(t, "0 todos", count())
+
+	click("add")
+	assert.Equal(t, "todo 1", items(), "expected the each block to be built anew")
+	assert.Equal(t, "1 todos", count(), "expected method calls to depend on the whole instance")
+	first := r.Query(flamingotest.ByText("todo 1"))
+	require.NotNil(t, first)
+	assert.True(t, first.Mounted())
+
+	click("add")
+	assert.Equal(t, "todo 2todo 1", items())
+	assert.Same(t, first, r.Query(flamingotest.ByText("todo 1")), "expected keyed elements to be kept")
+
+	click("select")
+	selected, _ := first.Attribute("data-selected")
+	assert.Equal(t, true, selected, "expected kept elements to take the attributes built anew")
+	assert.Same(t, first, r.Query(flamingotest.ByAttr("data-selected", true)))
+
+	click("load")
+	assert.Nil(t, r.Query(flamingotest.ByTag("ul")), "expected the if block to switch branches")
+	assert.NotNil(t, r.Query(flamingotest.ByText("Loading")))
+	assert.False(t, first.Mounted(), "expected the elements of the branch left to be removed")
+
+	click("load")
+	assert.Equal(t, "todo 2todo 1", items())
+	assert.Nil(t, r.Query(flamingotest.ByText("Loading")))
+}
+
+func TestBlocksUpdateHTML(t *testing.T) {
+	r := html.NewRenderer()
+	TodosComp(r, TodosProps{Label: "todo"})
+	assert.Equal(t, `<section><button id="add">Add</button> <button id="select">Select</button> <button id="load">Load</button><ul><li>Nothing to do</li></ul><p id="count">0 todos</p></section>`, r.String(), "expected the texts delimiting blocks to render nothing")
+}
@@ -0,0 +1,51 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/flamingotest"
+	"github.com/tifye/flamingo/html"
+)
+
+func TestTopLevelBlocks(t *testing.T) {
+	test := func(t *testing.T, r *flamingotest.Renderer) {
+		click := func(id string) {
+			t.Helper()
+			require.NoError(t, r.Query(flamingotest.ByAttr("id", id)).Click())
+			r.Flush()
+		}
+		assert.Equal(t, "LoadAdd", r.Text())
+
+		click("load")
+		assert.Equal(t, "LoadLoadingAdd", r.Text(), "expected the if block to be built anew")
+		click("add")
+		click("add")
+		assert.Equal(t, "LoadLoadingitem 1item 2Add", r.Text(), "expected the each block to be built anew")
+		click("load")
+		assert.Equal(t, "Loaditem 1item 2Add", r.Text())
+	}
+
+	t.Run("roots", func(t *testing.T) {
+		r := flamingotest.NewRenderer()
+		TopComp(r, TopProps{})
+		test(t, r)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		r := flamingotest.NewRenderer()
+		div := r.NewComponent("div")
+		for _, c := range TopTree(r, TopProps{}, nil) {
+			r.Append(div, c)
+		}
+		r.Render(div)
+		test(t, r)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		r := html.NewRenderer()
+		TopComp(r, TopProps{})
+		assert.Equal(t, `<button id="load">Load</button><button id="add">Add</button>`, r.String())
+	})
+}
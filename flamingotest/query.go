@@ -0,0 +1,77 @@
+package flamingotest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tifye/flamingo/render"
+)
+
+// Matcher reports whether a node matches a query.
+type Matcher func(n *Node) bool
+
+// ByTag matches nodes with the tag name.
+func ByTag(name string) Matcher {
+	return func(n *Node) bool {
+		return n.name == name
+	}
+}
+
+// ByAttr matches nodes whose attribute key is set to val.
+func ByAttr(key string, val any) Matcher {
+	return func(n *Node) bool {
+		v, ok := n.attrs[key]
+		return ok && fmt.Sprint(v) == fmt.Sprint(val)
+	}
+}
+
+// HasAttr matches nodes which have the attribute key set.
+func HasAttr(key string) Matcher {
+	return func(n *Node) bool {
+		_, ok := n.attrs[key]
+		return ok
+	}
+}
+
+// ByText matches elements whose own text, ignoring surrounding
+// white space and with each run of white space read as a single
+// space, equals text. The own text of an element is its innerText
+// attribute, or else the text of its text children.
+func ByText(text string) Matcher {
+	return func(n *Node) bool {
+		if n.name == render.TextName {
+			return false
+		}
+		if v, ok := n.attrs["innerText"]; ok {
+			return normalizeSpace(fmt.Sprint(v)) == text
+		}
+
+		var own strings.Builder
+		found := false
+		for _, c := range n.Children() {
+			if c := c.(*Node); c.name == render.TextName {
+				own.WriteString(c.Text())
+				found = true
+			}
+		}
+		return found && normalizeSpace(own.String()) == text
+	}
+}
+
+// normalizeSpace trims the white space around text
+// and replaces each run of it within by a space.
+func normalizeSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// And matches nodes matching all of ms.
+func And(ms ...Matcher) Matcher {
+	return func(n *Node) bool {
+		for _, m := range ms {
+			if !m(n) {
+				return false
+			}
+		}
+		return true
+	}
+}
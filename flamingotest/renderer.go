@@ -0,0 +1,281 @@
+// Package flamingotest provides an in-memory render.Renderer
+// together with helpers for querying and interacting with the
+// rendered tree, so components can be unit tested with go test.
+package flamingotest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tifye/flamingo/render"
+)
+
+// Node is a render.Component recorded by the Renderer.
+type Node struct {
+	render.Links
+	name    string
+	keys    []string // attribute keys in the order they were first set
+	attrs   map[string]any
+	mounted bool
+}
+
+func (n *Node) Name() string {
+	return n.name
+}
+
+func (n *Node) Attributes() map[string]any {
+	return n.attrs
+}
+
+func (n *Node) SetAttribute(key string, val any) {
+	if n.attrs == nil {
+		n.attrs = make(map[string]any)
+	}
+	if _, ok := n.attrs[key]; !ok {
+		n.keys = append(n.keys, key)
+	}
+	n.attrs[key] = val
+}
+
+// Attribute returns the value of the attribute key
+// and whether it is set.
+func (n *Node) Attribute(key string) (any, bool) {
+	val, ok := n.attrs[key]
+	return val, ok
+}
+
+// Parent returns the node n was appended to, if any.
+func (n *Node) Parent() *Node {
+	p, _ := render.Parent(n).(*Node)
+	if p != nil && p.name == documentName {
+		return nil
+	}
+	return p
+}
+
+// Mounted reports whether n is part of the rendered tree.
+func (n *Node) Mounted() bool {
+	return n.mounted
+}
+
+// Text returns the text of n followed by the text of its
+// descendants, as the textContent of a DOM node.
+func (n *Node) Text() string {
+	if n.name == render.TextName {
+		return fmt.Sprint(n.attrs[render.TextAttribute])
+	}
+
+	var text strings.Builder
+	if v, ok := n.attrs["innerText"]; ok {
+		text.WriteString(fmt.Sprint(v))
+	}
+	for _, c := range n.Children() {
+		text.WriteString(c.(*Node).Text())
+	}
+	return text.String()
+}
+
+// Fire invokes the on:event handler of n.
+func (n *Node) Fire(event string) error {
+	val, ok := n.attrs["on:"+event]
+	if !ok {
+		return fmt.Errorf("<%s> has no on:%s handler", n.name, event)
+	}
+	handler, ok := val.(func())
+	if !ok {
+		return fmt.Errorf("<%s> on:%s handler is %T, expected func()", n.name, event, val)
+	}
+	handler()
+	return nil
+}
+
+// Click invokes the on:click handler of n.
+func (n *Node) Click() error {
+	return n.Fire("click")
+}
+
+// Bind simulates the user changing the bound property prop of
+// n to val, setting the attribute and invoking the bind:prop
+// callback of n.
+func (n *Node) Bind(prop string, val any) error {
+	v, ok := n.attrs["bind:"+prop]
+	if !ok {
+		return fmt.Errorf("<%s> has no bind:%s callback", n.name, prop)
+	}
+	callback, ok := v.(func(val any))
+	if !ok {
+		return fmt.Errorf("<%s> bind:%s callback is %T, expected func(val any)", n.name, prop, v)
+	}
+	n.SetAttribute(prop, val)
+	callback(val)
+	return nil
+}
+
+// Query returns the first node, in document order, of the
+// subtree rooted at n matching m, or nil if there is none.
+func (n *Node) Query(m Matcher) *Node {
+	var found *Node
+	n.walk(func(nn *Node) bool {
+		if m(nn) {
+			found = nn
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// QueryAll returns all nodes, in document order, of the
+// subtree rooted at n matching m.
+func (n *Node) QueryAll(m Matcher) []*Node {
+	found := make([]*Node, 0)
+	n.walk(func(nn *Node) bool {
+		if m(nn) {
+			found = append(found, nn)
+		}
+		return true
+	})
+	return found
+}
+
+// walk visits n and its descendants in document order
+// until visit returns false.
+func (n *Node) walk(visit func(*Node) bool) bool {
+	if !visit(n) {
+		return false
+	}
+	for _, c := range n.Children() {
+		if !c.(*Node).walk(visit) {
+			return false
+		}
+	}
+	return true
+}
+
+// Renderer is a render.Renderer recording the component tree in
+// memory. Rendering a component mounts it as if it were inserted
+// into a document, invoking the lifecycle hooks of attached
+// component instances. Updates scheduled by component instances
+// are only applied by calling Flush, so tests control when the
+// tree changes.
+type Renderer struct {
+	render.Lifecycle
+	render.Scheduler
+
+	// document holds the rendered roots as its children,
+	// so they are rearranged like those of any node
+	document *Node
+}
+
+// documentName is the name of the node holding the roots.
+const documentName = "#document"
+
+var _ render.Renderer = (*Renderer)(nil)
+
+func NewRenderer() *Renderer {
+	return &Renderer{document: &Node{name: documentName, mounted: true}}
+}
+
+func (r *Renderer) NewComponent(name string) render.Component {
+	return &Node{name: name}
+}
+
+func (r *Renderer) NewText(text string) render.Component {
+	n := &Node{name: render.TextName}
+	n.SetAttribute(render.TextAttribute, text)
+	return n
+}
+
+func (r *Renderer) Render(comps ...render.Component) {
+	for _, c := range comps {
+		n := mustNode(c)
+		render.Link(r.document, n, nil)
+		r.mount(n)
+	}
+}
+
+func (r *Renderer) Append(parent render.Component, child render.Component) {
+	r.Insert(parent, child, nil)
+}
+
+// Insert moves child, or inserts it, into the children of parent
+// before the child before, or at their end if before is nil,
+// mounting it if it is inserted into the rendered tree.
+func (r *Renderer) Insert(parent render.Component, child render.Component, before render.Component) {
+	p, c := mustNode(parent), mustNode(child)
+	wasMounted := c.mounted
+	render.Link(p, c, before)
+	if p.mounted && !wasMounted {
+		r.mount(c)
+	}
+}
+
+func (r *Renderer) Reconcile(parent render.Component, children []render.Component) []render.Component {
+	return render.Reconcile(r, mustNode(parent), children)
+}
+
+func (r *Renderer) Remove(comp render.Component) {
+	c := mustNode(comp)
+	if c.mounted {
+		r.Unmount(c)
+		c.walk(func(n *Node) bool {
+			n.mounted = false
+			return true
+		})
+	}
+	render.Unlink(c)
+}
+
+// Roots returns the nodes rendered as roots.
+func (r *Renderer) Roots() []*Node {
+	roots := make([]*Node, 0, len(r.document.Children()))
+	for _, c := range r.document.Children() {
+		roots = append(roots, c.(*Node))
+	}
+	return roots
+}
+
+// Query returns the first rendered node, in document
+// order, matching m, or nil if there is none.
+func (r *Renderer) Query(m Matcher) *Node {
+	for _, root := range r.Roots() {
+		if n := root.Query(m); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// QueryAll returns all rendered nodes, in document order, matching m.
+func (r *Renderer) QueryAll(m Matcher) []*Node {
+	found := make([]*Node, 0)
+	for _, root := range r.Roots() {
+		found = append(found, root.QueryAll(m)...)
+	}
+	return found
+}
+
+// Text returns the text of all rendered nodes.
+func (r *Renderer) Text() string {
+	var text strings.Builder
+	for _, root := range r.Roots() {
+		text.WriteString(root.Text())
+	}
+	return text.String()
+}
+
+func (r *Renderer) mount(n *Node) {
+	n.walk(func(nn *Node) bool {
+		nn.mounted = true
+		return true
+	})
+	r.Mount(n)
+}
+
+func mustNode(c render.Component) *Node {
+	n, ok := c.(*Node)
+	if !ok {
+		panic(fmt.Sprintf("invalid comp type %T", c))
+	}
+	return n
+}
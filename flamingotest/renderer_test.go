@@ -0,0 +1,179 @@
+package flamingotest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/render"
+)
+
+// Counter mirrors the output of the compiler for
+//
+//	<div class="counter">
+//	    <span>{c.count}</span>
+//	    <button on:click={c.increment}>+</button>
+//	    <input bind:value={c.setLabel}/>
+//	</div>
+type Counter struct {
+	count   int
+	label   string
+	mounted bool
+}
+
+func (c *Counter) increment()       { c.count++ }
+func (c *Counter) setLabel(val any) { c.label = val.(string) }
+func (c *Counter) OnMount()         { c.mounted = true }
+func (c *Counter) OnUnmount()       { c.mounted = false }
+
+func CounterComp(renderer render.Renderer) *Counter {
+	c := &Counter{}
+
+	div1 := renderer.NewComponent("div")
+	div1.SetAttribute("class", "counter")
+	span2 := renderer.NewComponent("span")
+	text3 := renderer.NewText("")
+	text3.SetAttribute("text", c.count)
+	button4 := renderer.NewComponent("button")
+	button4.SetAttribute("on:click", c.increment)
+	text5 := renderer.NewText(`+`)
+	input6 := renderer.NewComponent("input")
+	input6.SetAttribute("bind:value", c.setLabel)
+
+	renderer.Append(div1, span2)
+	renderer.Append(span2, text3)
+	renderer.Append(div1, button4)
+	renderer.Append(button4, text5)
+	renderer.Append(div1, input6)
+	renderer.Attach(div1, c)
+	renderer.Render(div1)
+	return c
+}
+
+func TestRenderer(t *testing.T) {
+	r := NewRenderer()
+	c := CounterComp(r)
+	assert.True(t, c.mounted, "expected component to be mounted")
+
+	t.Run("query", func(t *testing.T) {
+		div := r.Query(ByTag("div"))
+		require.NotNil(t, div)
+		assert.Equal(t, div, r.Query(ByAttr("class", "counter")))
+		assert.Equal(t, "0+", div.Text())
+
+		button := r.Query(ByText("+"))
+		require.NotNil(t, button)
+		assert.Equal(t, "button", button.Name())
+		assert.Equal(t, div, button.Parent())
+
+		assert.Len(t, r.QueryAll(ByTag(render.TextName)), 2)
+		assert.Nil(t, r.Query(And(ByTag("span"), ByText("1"))))
+	})
+
+	t.Run("fire", func(t *testing.T) {
+		button := r.Query(ByTag("button"))
+		require.NoError(t, button.Click())
+		require.NoError(t, button.Click())
+		assert.Equal(t, 2, c.count)
+
+		assert.ErrorContains(t, button.Fire("keydown"), "<button> has no on:keydown handler")
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		input := r.Query(ByTag("input"))
+		require.NoError(t, input.Bind("value", "mino"))
+		assert.Equal(t, "mino", c.label)
+
+		val, ok := input.Attribute("value")
+		assert.True(t, ok)
+		assert.Equal(t, "mino", val)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		MatchSnapshot(t, r, "testdata/counter.snap")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		div := r.Query(ByTag("div"))
+		r.Remove(div)
+		assert.False(t, c.mounted, "expected component to be unmounted")
+		assert.False(t, div.Mounted())
+		assert.Empty(t, r.Roots())
+	})
+}
+
+type mountCounter struct{ mounts, unmounts int }
+
+func (m *mountCounter) OnMount()   { m.mounts++ }
+func (m *mountCounter) OnUnmount() { m.unmounts++ }
+
+func TestReconcile(t *testing.T) {
+	r := NewRenderer()
+	ul := r.NewComponent("ul")
+	counters := make(map[string]*mountCounter)
+	li := func(key string) render.Component {
+		c := r.NewComponent("li")
+		c.SetAttribute(render.KeyAttribute, key)
+		c.SetAttribute("innerText", key)
+		counters[key] = &mountCounter{}
+		r.Attach(c, counters[key])
+		return c
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		r.Append(ul, li(key))
+	}
+	r.Render(ul)
+
+	kept := counters["b"]
+	r.Reconcile(ul, []render.Component{li("d"), li("c"), li("b")})
+	assert.Equal(t, "dcb", r.Text())
+	assert.Equal(t, &mountCounter{mounts: 1}, kept, "expected kept child not to be mounted again")
+	assert.Equal(t, 1, counters["d"].mounts)
+
+	nodes := r.QueryAll(ByTag("li"))
+	require.Len(t, nodes, 3)
+	for _, n := range nodes {
+		assert.True(t, n.Mounted())
+		assert.Equal(t, ul, n.Parent())
+	}
+}
+
+func TestText(t *testing.T) {
+	r := NewRenderer()
+	p := r.NewComponent("p")
+	b := r.NewComponent("b")
+	r.Append(p, r.NewText("Hello "))
+	r.Append(p, b)
+	r.Append(b, r.NewText("world"))
+	r.Append(p, r.NewText(" again, "))
+	name := r.NewText("")
+	r.Append(p, name)
+	r.Render(p)
+
+	name.SetAttribute(render.TextAttribute, 42)
+	assert.Equal(t, "Hello world again, 42", r.Text(), "expected text to keep its place among the elements")
+	assert.Equal(t, b, r.Query(ByText("world")))
+	assert.Nil(t, r.Query(ByText("Hello")), "expected the own text of p to include all of its text children")
+	assert.Equal(t, p, r.Query(ByText("Hello again, 42")), "expected runs of white space to match a single space")
+	assert.Equal(t, "<p>\n  \"Hello \"\n  <b>\n    \"world\"\n  </b>\n  \" again, \"\n  \"42\"\n</p>\n", r.Snapshot())
+}
+
+func TestScheduledUpdates(t *testing.T) {
+	r := NewRenderer()
+	state := render.NewReactive(r)
+	count := 0
+
+	span := r.NewComponent("span")
+	state.Bind([]string{"count"}, func() {
+		span.SetAttribute("innerText", count)
+	})
+	state.SetRoot(span)
+	r.Render(span)
+
+	count = 2
+	state.Invalidate("count")
+	assert.Equal(t, "0", r.Text(), "expected the update to wait for Flush")
+
+	r.Flush()
+	assert.Equal(t, "2", r.Text())
+}
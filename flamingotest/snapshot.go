@@ -0,0 +1,98 @@
+package flamingotest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tifye/flamingo/render"
+)
+
+// UpdateEnv is the environment variable which, when set to a
+// non-empty value, makes MatchSnapshot write snapshots instead
+// of comparing against them.
+const UpdateEnv = "FLAMINGO_UPDATE_SNAPSHOTS"
+
+// Snapshot returns a deterministic textual representation of
+// the rendered tree. Handlers are written as their type as
+// function values cannot be compared.
+func (r *Renderer) Snapshot() string {
+	var sb strings.Builder
+	for _, root := range r.Roots() {
+		root.snapshot(&sb, 0)
+	}
+	return sb.String()
+}
+
+func (n *Node) snapshot(sb *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.name == render.TextName {
+		// Empty texts, such as those delimiting
+		// the blocks of components, show nothing
+		if text := n.Text(); text != "" {
+			sb.WriteString(indent + strconv.Quote(text) + "\n")
+		}
+		return
+	}
+
+	sb.WriteString(indent + "<" + n.name)
+	for _, key := range n.keys {
+		if key == "innerText" {
+			continue
+		}
+		fmt.Fprintf(sb, " %s=%s", key, snapshotValue(n.attrs[key]))
+	}
+	sb.WriteString(">\n")
+
+	if text, ok := n.attrs["innerText"]; ok {
+		sb.WriteString(indent + "  " + strconv.Quote(fmt.Sprint(text)) + "\n")
+	}
+	for _, c := range n.Children() {
+		c.(*Node).snapshot(sb, depth+1)
+	}
+
+	sb.WriteString(indent + "</" + n.name + ">\n")
+}
+
+func snapshotValue(val any) string {
+	if val == nil {
+		return "{nil}"
+	}
+	if reflect.TypeOf(val).Kind() == reflect.Func {
+		return fmt.Sprintf("{%T}", val)
+	}
+	if s, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprintf("{%#v}", val)
+}
+
+// MatchSnapshot compares the snapshot of r with the contents
+// of file, failing t if they differ. The file is written
+// instead when UpdateEnv is set or the file does not exist.
+func MatchSnapshot(t testing.TB, r *Renderer, file string) {
+	t.Helper()
+
+	got := r.Snapshot()
+	want, err := os.ReadFile(file)
+	if os.Getenv(UpdateEnv) != "" || os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("create snapshot dir: %s", err)
+		}
+		if err := os.WriteFile(file, []byte(got), 0644); err != nil {
+			t.Fatalf("write snapshot: %s", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("read snapshot: %s", err)
+	}
+
+	if got != string(want) {
+		t.Errorf("snapshot %s does not match, set %s=1 to update\n--- want\n%s\n--- got\n%s", file, UpdateEnv, want, got)
+	}
+}
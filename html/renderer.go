@@ -0,0 +1,240 @@
+// Package html implements a render.Renderer that builds the
+// component tree in memory and writes it out as HTML, allowing
+// compiled components to be rendered on the server.
+package html
+
+import (
+	"fmt"
+	stdhtml "html"
+	"io"
+	"strings"
+
+	"github.com/tifye/flamingo/render"
+)
+
+// voidElements have no closing tag and cannot have children.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true,
+	"embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "source": true, "track": true,
+	"wbr": true,
+}
+
+// booleanAttributes are present when true and omitted when false.
+var booleanAttributes = map[string]bool{
+	"allowfullscreen": true, "async": true, "autofocus": true,
+	"autoplay": true, "checked": true, "controls": true,
+	"default": true, "defer": true, "disabled": true,
+	"formnovalidate": true, "hidden": true, "inert": true,
+	"ismap": true, "loop": true, "multiple": true, "muted": true,
+	"nomodule": true, "novalidate": true, "open": true,
+	"playsinline": true, "readonly": true, "required": true,
+	"reversed": true, "selected": true,
+}
+
+type Element struct {
+	render.Links
+	name  string
+	keys  []string // attribute keys in the order they were first set
+	attrs map[string]any
+}
+
+func (e *Element) Name() string {
+	return e.name
+}
+
+func (e *Element) Attributes() map[string]any {
+	return e.attrs
+}
+
+func (e *Element) SetAttribute(key string, val any) {
+	if e.attrs == nil {
+		e.attrs = make(map[string]any)
+	}
+	if _, ok := e.attrs[key]; !ok {
+		e.keys = append(e.keys, key)
+	}
+	e.attrs[key] = val
+}
+
+// WriteTo writes the element and its descendants as HTML to w.
+func (e *Element) WriteTo(w io.Writer) (int64, error) {
+	hw := &htmlWriter{w: w}
+	hw.element(e)
+	return hw.n, hw.err
+}
+
+// Renderer is a render.Renderer producing HTML. Output is never
+// mounted in a document, so only the render.Unmounter and
+// render.Updater hooks of attached instances are invoked.
+type Renderer struct {
+	render.Lifecycle
+	render.Scheduler
+
+	// document holds the rendered roots as its children,
+	// so they are rearranged like those of any component
+	document *Element
+}
+
+var _ render.Renderer = (*Renderer)(nil)
+
+func NewRenderer() *Renderer {
+	return &Renderer{document: &Element{}}
+}
+
+func (r *Renderer) NewComponent(name string) render.Component {
+	return &Element{name: name}
+}
+
+func (r *Renderer) NewText(text string) render.Component {
+	e := &Element{name: render.TextName}
+	e.SetAttribute(render.TextAttribute, text)
+	return e
+}
+
+func (r *Renderer) Render(comps ...render.Component) {
+	for _, c := range comps {
+		render.Link(r.document, mustElement(c), nil)
+	}
+}
+
+func (r *Renderer) Append(parent render.Component, child render.Component) {
+	r.Insert(parent, child, nil)
+}
+
+// Insert moves child, or inserts it, into the children of parent
+// before the child before, or at their end if before is nil.
+func (r *Renderer) Insert(parent render.Component, child render.Component, before render.Component) {
+	render.Link(mustElement(parent), mustElement(child), before)
+}
+
+func (r *Renderer) Reconcile(parent render.Component, children []render.Component) []render.Component {
+	return render.Reconcile(r, mustElement(parent), children)
+}
+
+func (r *Renderer) Remove(comp render.Component) {
+	c := mustElement(comp)
+	r.Unmount(c)
+	render.Unlink(c)
+}
+
+// Roots returns the components rendered as roots.
+func (r *Renderer) Roots() []render.Component {
+	return r.document.Children()
+}
+
+// WriteTo writes the rendered roots as HTML to w.
+func (r *Renderer) WriteTo(w io.Writer) (int64, error) {
+	hw := &htmlWriter{w: w}
+	for _, c := range r.document.Children() {
+		hw.element(c.(*Element))
+	}
+	return hw.n, hw.err
+}
+
+// String returns the rendered roots as HTML.
+func (r *Renderer) String() string {
+	var sb strings.Builder
+	_, _ = r.WriteTo(&sb)
+	return sb.String()
+}
+
+func mustElement(c render.Component) *Element {
+	el, ok := c.(*Element)
+	if !ok {
+		panic(fmt.Sprintf("invalid comp type %T", c))
+	}
+	return el
+}
+
+// htmlWriter writes elements to w, keeping
+// track of the bytes written and the first error.
+type htmlWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (hw *htmlWriter) write(s string) {
+	if hw.err != nil {
+		return
+	}
+	n, err := io.WriteString(hw.w, s)
+	hw.n += int64(n)
+	hw.err = err
+}
+
+func (hw *htmlWriter) element(e *Element) {
+	if e.name == render.TextName {
+		hw.write(stdhtml.EscapeString(fmt.Sprint(e.attrs[render.TextAttribute])))
+		return
+	}
+
+	hw.write("<")
+	hw.write(e.name)
+
+	text, hasText := "", false
+	for _, key := range e.keys {
+		val := e.attrs[key]
+		switch {
+		case key == "innerText":
+			text, hasText = fmt.Sprint(val), true
+			continue
+		case key == render.KeyAttribute, strings.HasPrefix(key, "on:"), strings.HasPrefix(key, "bind:"):
+			continue
+		case !validAttributeName(key):
+			continue
+		}
+		hw.attribute(key, val)
+	}
+	hw.write(">")
+
+	if voidElements[e.name] {
+		return
+	}
+
+	if hasText {
+		hw.write(stdhtml.EscapeString(text))
+	}
+	for _, c := range e.Children() {
+		hw.element(c.(*Element))
+	}
+
+	hw.write("</")
+	hw.write(e.name)
+	hw.write(">")
+}
+
+func (hw *htmlWriter) attribute(key string, val any) {
+	switch v := val.(type) {
+	case nil, func(), func(any):
+		return
+	case bool:
+		if booleanAttributes[key] {
+			if v {
+				hw.write(" " + key)
+			}
+			return
+		}
+	case string:
+		if booleanAttributes[key] {
+			// Bare attributes in templates are set to "true"
+			if v == "true" || v == "" || v == key {
+				hw.write(" " + key)
+			}
+			return
+		}
+	}
+
+	hw.write(" ")
+	hw.write(key)
+	hw.write(`="`)
+	hw.write(stdhtml.EscapeString(fmt.Sprint(val)))
+	hw.write(`"`)
+}
+
+// validAttributeName reports whether name can be written
+// as an attribute name without breaking out of the tag.
+func validAttributeName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n\f\r\"'>/=<")
+}
@@ -0,0 +1,136 @@
+package html
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/render"
+)
+
+func TestRenderer(t *testing.T) {
+	t.Run("nested elements", func(t *testing.T) {
+		r := NewRenderer()
+		div := r.NewComponent("div")
+		div.SetAttribute("class", "bg-rose-500")
+		span := r.NewComponent("span")
+		span.SetAttribute("innerText", "mino")
+		label := r.NewComponent("label")
+		label.SetAttribute("innerText", 42)
+		r.Append(div, span)
+		r.Append(div, label)
+		r.Render(div)
+
+		assert.Equal(t, `<div class="bg-rose-500"><span>mino</span><label>42</label></div>`, r.String())
+	})
+
+	t.Run("escaping", func(t *testing.T) {
+		r := NewRenderer()
+		a := r.NewComponent("a")
+		a.SetAttribute("title", `"quoted" & <b>`)
+		a.SetAttribute("innerText", `<script>alert("meep")</script>`)
+		a.SetAttribute(`onclick="x"`, "dropped")
+		r.Render(a)
+
+		assert.Equal(t, `<a title="&#34;quoted&#34; &amp; &lt;b&gt;">&lt;script&gt;alert(&#34;meep&#34;)&lt;/script&gt;</a>`, r.String())
+	})
+
+	t.Run("text", func(t *testing.T) {
+		r := NewRenderer()
+		p := r.NewComponent("p")
+		b := r.NewComponent("b")
+		count := r.NewText("")
+		r.Append(p, r.NewText("Hello "))
+		r.Append(p, b)
+		r.Append(b, r.NewText("<world>"))
+		r.Append(p, count)
+		r.Render(p)
+		count.SetAttribute(render.TextAttribute, 42)
+
+		assert.Equal(t, `<p>Hello <b>&lt;world&gt;</b>42</p>`, r.String())
+	})
+
+	t.Run("void and boolean attributes", func(t *testing.T) {
+		r := NewRenderer()
+		input := r.NewComponent("input")
+		input.SetAttribute("type", "checkbox")
+		input.SetAttribute("checked", true)
+		input.SetAttribute("disabled", false)
+		input.SetAttribute("required", "true")
+		input.SetAttribute("aria-hidden", true)
+		r.Render(input, r.NewComponent("br"))
+
+		assert.Equal(t, `<input type="checkbox" checked required aria-hidden="true"><br>`, r.String())
+	})
+
+	t.Run("directives are dropped", func(t *testing.T) {
+		r := NewRenderer()
+		button := r.NewComponent("button")
+		button.SetAttribute("on:click", func() {})
+		button.SetAttribute("bind:value", func(any) {})
+		button.SetAttribute("innerText", "click")
+		r.Render(button)
+
+		assert.Equal(t, `<button>click</button>`, r.String())
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		r := NewRenderer()
+		ul := r.NewComponent("ul")
+		li1, li2 := r.NewComponent("li"), r.NewComponent("li")
+		r.Append(ul, li1)
+		r.Append(ul, li2)
+		r.Render(ul)
+
+		var unmounted bool
+		r.Attach(li1, unmounter(func() { unmounted = true }))
+		r.Remove(li1)
+
+		assert.True(t, unmounted)
+		require.Len(t, ul.Children(), 1)
+		assert.Equal(t, `<ul><li></li></ul>`, r.String())
+	})
+
+	t.Run("reconcile", func(t *testing.T) {
+		r := NewRenderer()
+		ul := r.NewComponent("ul")
+		li := func(key string) render.Component {
+			c := r.NewComponent("li")
+			c.SetAttribute(render.KeyAttribute, key)
+			c.SetAttribute("innerText", key)
+			return c
+		}
+		a, b := li("a"), li("b")
+		r.Append(ul, a)
+		r.Append(ul, b)
+		r.Render(ul)
+
+		result := r.Reconcile(ul, []render.Component{li("c"), li("b"), li("a")})
+		assert.Same(t, b, result[1])
+		assert.Same(t, a, result[2])
+		assert.Equal(t, `<ul><li>c</li><li>b</li><li>a</li></ul>`, r.String())
+
+		changed := li("a")
+		changed.SetAttribute("class", "done")
+		r.Append(changed, r.NewText("A"))
+		result = r.Reconcile(ul, []render.Component{changed})
+		assert.Same(t, a, result[0])
+		assert.Equal(t, `<ul><li class="done">aA</li></ul>`, r.String(), "expected the kept element to take the attributes and children of the new one")
+	})
+
+	t.Run("write to", func(t *testing.T) {
+		r := NewRenderer()
+		r.Render(r.NewComponent("p"))
+
+		var sb strings.Builder
+		n, err := r.WriteTo(&sb)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len("<p></p>")), n)
+		assert.Equal(t, "<p></p>", sb.String())
+	})
+}
+
+type unmounter func()
+
+func (u unmounter) OnUnmount() { u() }
@@ -0,0 +1,46 @@
+package lexer
+
+import (
+	source "go/token"
+	"testing"
+
+	"github.com/tifye/flamingo/token"
+)
+
+func FuzzLexer(f *testing.F) {
+	seeds := []string{
+		``,
+		`<div class="p-4">mino</div>`,
+		"---\nfunc _() {}\n---\n<test></test>",
+		`<span>Hello {c.Name}</span>`,
+		`<a class={c.classes()} href="/">`,
+		`<input on:click={c.handleClick} bind:value={c.setText}/>`,
+		`< div>`,
+		`<>`,
+		`<div =x>`,
+		`<p>{"}"</p>`,
+		"---\n",
+		`<</>`,
+		`{#if x}<p>{:else}{/if}`,
+		`{#each c.items as item, i (item.ID)}{/each}`,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		fset := source.NewFileSet()
+		file := fset.AddFile("fuzz.flamingo", fset.Base(), len(input))
+		l := NewLexer(file, input)
+
+		// Every token but EOF and ERROR consumes at least one byte,
+		// and input is consumed between any two errors
+		for range 2*len(input) + 2 {
+			tok := l.NextToken()
+			if tok.Type == token.EOF {
+				return
+			}
+		}
+		t.Fatalf("lexer did not reach EOF")
+	})
+}
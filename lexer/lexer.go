@@ -17,6 +17,13 @@ const (
 
 type stateFunc func(*Lexer) stateFunc
 
+// blockKeywords maps the keywords of block tags to their tokens
+var blockKeywords = map[string]token.TokenType{
+	"if":   token.IF,
+	"else": token.ELSE,
+	"each": token.EACH,
+}
+
 type Lexer struct {
 	file   *source.File
 	input  string
@@ -50,12 +57,21 @@ func (l *Lexer) WithState(state stateFunc) *Lexer {
 	return l
 }
 
+// File returns the file the lexer resolves positions in.
+func (l *Lexer) File() *source.File {
+	return l.file
+}
+
 func (l *Lexer) NextToken() token.Token {
 	for {
 		select {
 		case item := <-l.tokens:
 			return item
 		default:
+			// Lexing stops after EOF or an unrecoverable error
+			if l.state == nil {
+				return token.Token{Pos: l.file.Pos(l.pos), Type: token.EOF}
+			}
 			l.state = l.state(l)
 		}
 	}
@@ -63,7 +79,7 @@ func (l *Lexer) NextToken() token.Token {
 
 func (l *Lexer) emit(typ token.TokenType) {
 	if typ == token.EOF {
-		tok := token.Token{Type: typ}
+		tok := token.Token{Pos: l.file.Pos(l.pos), Type: typ}
 		l.tokens <- tok
 		l.start = l.pos
 		return
@@ -84,11 +100,8 @@ func (l *Lexer) emit(typ token.TokenType) {
 func (l *Lexer) next() rune {
 	if l.pos >= len(l.input) {
 		l.pos = len(l.input)
-		r, _ := utf8.DecodeRuneInString(l.input[len(l.input)-1:])
-		if r == '\n' {
-			l.lineStart = l.pos
-			l.file.AddLine(l.pos)
-		}
+		// Nothing was consumed so there is nothing to back up
+		l.width = 0
 		return eof
 	}
 
@@ -144,6 +157,7 @@ func (l *Lexer) runUntil(valid string) {
 
 func (l *Lexer) errorf(format string, args ...interface{}) stateFunc {
 	l.tokens <- token.Token{
+		Pos:     l.file.Pos(l.pos),
 		Type:    token.ERROR,
 		Literal: fmt.Sprintf(format, args...),
 	}
@@ -189,52 +203,50 @@ func LexCodeBlock(l *Lexer) stateFunc {
 
 	//lint:ignore SA4000 Here we check for 3 '-' in a row
 	if !l.accept("-") || !l.accept("-") || !l.accept("-") {
-		return l.errorf("expected code fence '---'")
+		l.errorf("expected code fence '---'")
+		return lexTextRecover
 	}
 
 	l.emit(token.CODE_FENCE)
 
 	if !l.accept("\n") {
-		return l.errorf("expected new line after code fence")
+		l.errorf("expected new line after code fence")
+		l.runUntil("\n")
+		l.accept("\n")
 	}
 
 	// We don't want the initial '\n' to be
 	// registered as part of the Go code
 	l.discard()
 
-	for range 10_000 {
-		ch := l.next()
-
-		if ch == eof {
-			return l.errorf("unexpteced end of file, expected code fence")
-		}
-
-		if ch == '\n' && l.peek() == '-' {
-			break
-		}
-
+	// The code block ends at the first line starting with a fence
+	for !strings.HasPrefix(l.input[l.pos:], "---") {
 		l.runUntil("\n")
+		if l.next() == eof {
+			if l.pos > l.start {
+				l.emit(token.GO_CODE)
+			}
+			return l.errorf("unexpected end of file, expected code fence")
+		}
 	}
 
 	if l.pos > l.start {
 		l.emit(token.GO_CODE)
 	}
 
-	//lint:ignore SA4000 Here we check for 3 '-' in a row
-	if !l.accept("-") || !l.accept("-") || !l.accept("-") {
-		return l.errorf("expected code fence '---'")
-	}
-
+	l.pos += len("---")
 	l.emit(token.CODE_FENCE)
 
 	return LexText
 }
 
+// LexText lexes the text up to the next tag, expression or block
+// tag. The text is emitted as is, white space included, as whether
+// it separates the nodes around it depends on what they are.
 func LexText(l *Lexer) stateFunc {
 	assert.AssertNotNil(l)
 
-	l.skipWhitespace()
-	l.runUntil("<")
+	l.runUntil("<{")
 	if l.peek() == eof {
 		if l.pos > l.start {
 			l.emit(token.TEXT)
@@ -247,36 +259,244 @@ func LexText(l *Lexer) stateFunc {
 	if l.pos > l.start {
 		l.emit(token.TEXT)
 	}
+
+	if l.peek() == '{' {
+		if l.pos+1 < len(l.input) && strings.IndexByte("#:/", l.input[l.pos+1]) >= 0 {
+			return LexBlock
+		}
+		return LexExpression
+	}
 	return LexTagStart
 }
 
-func LexTagStart(l *Lexer) stateFunc {
+// LexExpression lexes a Go expression enclosed in braces,
+// emitting its contents, without the braces, as GO_EXPRESSION.
+func LexExpression(l *Lexer) stateFunc {
+	assert.AssertNotNil(l)
+
+	if !l.lexGoExpression() {
+		return nil
+	}
+	return LexText
+}
+
+// LexBlock lexes a block tag such as {#if cond}, {:else if cond},
+// {:else}, {/if} or {#each items as item}, emitting the opening of
+// the tag, its keywords, the Go expression following them, if any,
+// and the closing brace.
+func LexBlock(l *Lexer) stateFunc {
 	assert.AssertNotNil(l)
 
+	l.next() // skip '{'
+	switch l.next() {
+	case '#':
+		l.emit(token.BLOCK_OPEN)
+	case ':':
+		l.emit(token.BLOCK_CONTINUE)
+	default:
+		l.emit(token.BLOCK_CLOSE)
+	}
+
+	l.acceptRun("abcdefghijklmnopqrstuvwxyz")
+	if l.pos == l.start {
+		l.errorf("expected keyword after %q", l.input[l.pos-2:l.pos])
+		return lexBlockRecover
+	}
+	keyword := l.input[l.start:l.pos]
+	l.emitKeyword()
+
+	l.skipWhitespace()
+
+	// {:else if cond}
+	if keyword == "else" && strings.HasPrefix(l.input[l.pos:], "if") {
+		l.acceptRun("abcdefghijklmnopqrstuvwxyz")
+		l.emitKeyword()
+		l.skipWhitespace()
+	}
+
+	if l.peek() != '}' && !l.lexGoCode() {
+		return nil
+	}
+
+	if !l.accept("}") {
+		return l.errorf("unexpected end of file, expected '}' to close block tag")
+	}
+	l.emit(token.RIGHT_BRACE)
+
+	return LexText
+}
+
+// emitKeyword emits the word at the current position
+// as a block keyword, or as an IDENT if it is none.
+func (l *Lexer) emitKeyword() {
+	if typ, ok := blockKeywords[l.input[l.start:l.pos]]; ok {
+		l.emit(typ)
+		return
+	}
+	l.emit(token.IDENT)
+}
+
+// lexBlockRecover skips the rest of a malformed block
+// tag up to its '}' and continues lexing text.
+func lexBlockRecover(l *Lexer) stateFunc {
+	l.runUntil("}<")
+	l.discard()
+	if l.accept("}") {
+		l.emit(token.RIGHT_BRACE)
+	}
+	return LexText
+}
+
+// lexGoExpression consumes a brace enclosed Go expression
+// starting at the current '{'. Reports false, after emitting
+// an error, when the end of input is reached before the
+// expression is closed.
+func (l *Lexer) lexGoExpression() bool {
 	ch := l.next()
-	assert.Assert(ch == '<', fmt.Sprintf("expected '<', got: %s", strconv.QuoteRune(ch)))
+	assert.Assert(ch == '{', fmt.Sprintf("expected '{', got: %s", strconv.QuoteRune(ch)))
+	l.discard()
+
+	if !l.lexGoCode() {
+		return false
+	}
+
+	l.next() // skip '}'
+	l.discard()
+	return true
+}
+
+// lexGoCode consumes Go code up to the '}' closing it, emitting
+// it as GO_EXPRESSION. Braces nested inside the code, as well as
+// braces inside string and rune literals, do not close it. Reports
+// false, after emitting an error, when the end of input is reached
+// before the closing '}'.
+func (l *Lexer) lexGoCode() bool {
+	depth := 0
+	for {
+		switch ch := l.next(); ch {
+		case eof:
+			l.errorf("unexpected end of file, expected '}' to close expression")
+			return false
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			l.backup()
+			if strings.TrimSpace(l.input[l.start:l.pos]) == "" {
+				l.errorf("empty expression")
+			} else {
+				l.emit(token.GO_EXPRESSION)
+			}
+			return true
+		case '"', '\'', '`':
+			if !l.skipLiteral(ch) {
+				l.errorf("unexpected end of file, unterminated literal in expression")
+				return false
+			}
+		}
+	}
+}
+
+// skipLiteral consumes a Go string, raw string or rune literal
+// whose opening quote has already been consumed. It reports
+// whether the closing quote was found.
+func (l *Lexer) skipLiteral(quote rune) bool {
+	for {
+		switch ch := l.next(); ch {
+		case eof:
+			return false
+		case quote:
+			return true
+		case '\\':
+			if quote != '`' {
+				l.next()
+			}
+		}
+	}
+}
+
+// lexTextRecover skips input after an error up to the
+// next tag or expression and continues lexing text.
+func lexTextRecover(l *Lexer) stateFunc {
+	l.runUntil("<{")
+	l.discard()
+	return LexText
+}
+
+// lexTagRecover skips the rest of a malformed tag up to its '>',
+// which is still emitted so the parser can resynchronise on it,
+// and continues lexing the following text. A '<' before any '>'
+// is taken as the start of the next tag.
+func lexTagRecover(l *Lexer) stateFunc {
+	l.runUntil("<>")
+	l.discard()
+	if l.accept(">") {
+		l.emit(token.RIGHT_CHEVRON)
+	}
+	return LexText
+}
+
+// lexAttributeRecover skips a malformed attribute
+// and continues lexing the tag's remaining attributes.
+func lexAttributeRecover(l *Lexer) stateFunc {
+	l.runUntil(" \t\r\n/>")
+	l.discard()
+	l.skipWhitespace()
+
+	ch := l.peek()
+	if ch == '/' || ch == '>' {
+		return LexTagEnd
+	}
+
+	return LexAttribute
+}
+
+func LexTagStart(l *Lexer) stateFunc {
+	assert.AssertNotNil(l)
+
+	if ch := l.peek(); ch != '<' {
+		l.errorf("expected '<', got %s", strconv.QuoteRune(ch))
+		return lexTextRecover
+	}
+	l.next()
 	l.emit(token.LEFT_CHEVRON)
 
 	if l.accept("/") {
 		l.emit(token.SLASH)
 	}
 
-	l.runUntil(" />")
-	if l.peek() != eof {
-		l.emit(token.IDENT)
-	} else {
-		if l.pos > l.start {
-			l.emit(token.IDENT)
+	l.runUntil(" \t\r\n/<>")
+	if l.pos == l.start {
+		if l.peek() == eof {
+			l.emit(token.EOF)
+			return nil
 		}
+		l.errorf("expected tag name after '<'")
 
+		// A name separated from the '<' is taken as that of the
+		// tag so that its closing tag still matches it
+		if !strings.ContainsRune(" \t\r\n", l.peek()) {
+			return lexTagRecover
+		}
+		l.skipWhitespace()
+		l.runUntil(" \t\r\n/<>")
+		if l.pos == l.start {
+			return lexTagRecover
+		}
+	}
+	l.emit(token.IDENT)
+	if l.peek() == eof {
 		l.emit(token.EOF)
 		return nil
 	}
 
-	// todo: check all types of empty characters
-	l.acceptRun(" ")
+	l.skipWhitespace()
 
-	ch = l.peek()
+	ch := l.peek()
 	if ch == '/' || ch == '>' {
 		return LexTagEnd
 	}
@@ -285,24 +505,75 @@ func LexTagStart(l *Lexer) stateFunc {
 }
 
 func LexAttribute(l *Lexer) stateFunc {
-	assert.Assert(!l.accept(" "), "expected no empty characters")
-
 	l.skipWhitespace()
 
-	l.runUntil("= />")
+	l.runUntil("=: \t\r\n/>")
 	if l.peek() == eof {
 		if l.pos > l.start {
 			l.emit(token.IDENT)
 		}
 		l.emit(token.EOF)
 		return nil
-	} else {
-		l.emit(token.IDENT)
 	}
 
+	// Namespaced names such as on:click are split into
+	// their namespace, a colon and the name itself.
+	if l.peek() == ':' {
+		if l.pos == l.start {
+			l.errorf("expected namespace before ':' in attribute name")
+			return lexAttributeRecover
+		}
+		if l.input[l.start:l.pos] == "on" {
+			l.emit(token.ON)
+		} else {
+			l.emit(token.IDENT)
+		}
+
+		l.accept(":")
+		l.emit(token.COLON)
+
+		l.runUntil("= \t\r\n/>")
+		if l.pos == l.start {
+			l.errorf("expected name after ':' in attribute name")
+			return lexAttributeRecover
+		}
+		if l.peek() == eof {
+			l.emit(token.IDENT)
+			l.emit(token.EOF)
+			return nil
+		}
+	}
+	if l.pos == l.start {
+		l.errorf("expected attribute name, got %s", strconv.QuoteRune(l.peek()))
+		l.next()
+		return lexAttributeRecover
+	}
+	l.emit(token.IDENT)
+
 	if l.accept("=") {
 		l.emit(token.ASSIGN)
-	} else {
+		return LexAttributeValue
+	}
+
+	l.skipWhitespace()
+
+	ch := l.peek()
+	if ch == '/' || ch == '>' {
+		return LexTagEnd
+	}
+
+	return LexAttribute
+}
+
+// LexAttributeValue lexes the value following an attribute's
+// '=', either a double quoted string or a brace enclosed
+// Go expression.
+func LexAttributeValue(l *Lexer) stateFunc {
+	if l.peek() == '{' {
+		if !l.lexGoExpression() {
+			return nil
+		}
+
 		l.skipWhitespace()
 
 		ch := l.peek()
@@ -316,7 +587,8 @@ func LexAttribute(l *Lexer) stateFunc {
 	if l.accept(`"`) {
 		l.emit(token.QUOTE)
 	} else {
-		return l.errorf(`expected quote(") after attribute assign`)
+		l.errorf(`expected quote(") or '{' after attribute assign`)
+		return lexAttributeRecover
 	}
 
 	l.runUntil(`"`)
@@ -328,11 +600,9 @@ func LexAttribute(l *Lexer) stateFunc {
 		return nil
 	}
 
-	if l.accept(`"`) {
-		l.emit(token.QUOTE)
-	} else {
-		return l.errorf(`expected quote(") to end attribute value`)
-	}
+	// runUntil stops at either EOF or the closing quote
+	l.accept(`"`)
+	l.emit(token.QUOTE)
 
 	l.skipWhitespace()
 
@@ -345,14 +615,12 @@ func LexAttribute(l *Lexer) stateFunc {
 }
 
 func LexTagEnd(l *Lexer) stateFunc {
-	ch := l.next()
-	assert.Assert(ch == '/' || ch == '>', "expect next rune to either be '/' or '>'")
-
-	switch ch {
+	switch ch := l.next(); ch {
 	case '/':
 		l.emit(token.SLASH)
 		if !l.accept(">") {
-			return l.errorf("expected '>' immediately after self closing '/'")
+			l.errorf("expected '>' immediately after self closing '/'")
+			return lexTagRecover
 		}
 		l.emit(token.RIGHT_CHEVRON)
 		return LexText
@@ -362,6 +630,7 @@ func LexTagEnd(l *Lexer) stateFunc {
 		return LexText
 
 	default:
-		panic("unreachable")
+		l.errorf("expected '/' or '>' to end tag, got %s", strconv.QuoteRune(ch))
+		return lexTagRecover
 	}
 }
@@ -148,6 +148,7 @@ func _() {}
 		{token.CODE_FENCE},
 		{token.GO_CODE},
 		{token.CODE_FENCE},
+		{token.TEXT},
 		{token.LEFT_CHEVRON},
 		{token.IDENT},
 		{token.RIGHT_CHEVRON},
@@ -155,6 +156,7 @@ func _() {}
 		{token.SLASH},
 		{token.IDENT},
 		{token.RIGHT_CHEVRON},
+		{token.TEXT},
 		{token.EOF},
 	}
 
@@ -164,3 +166,309 @@ func _() {}
 		assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d, expected %s, got %s", i, tt.expectedType, tok.Type)
 	}
 }
+
+func TestGoExpression(t *testing.T) {
+	fset := source.NewFileSet()
+	t.Run(`<span>Hello {c.Name}</span>`, func(t *testing.T) {
+		input := `<span>Hello {c.Name}</span>`
+		f := fset.AddFile("", fset.Base(), len(input))
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.LEFT_CHEVRON, "<"},
+			{token.IDENT, "span"},
+			{token.RIGHT_CHEVRON, ">"},
+			{token.TEXT, "Hello "},
+			{token.GO_EXPRESSION, "c.Name"},
+			{token.LEFT_CHEVRON, "<"},
+			{token.SLASH, "/"},
+			{token.IDENT, "span"},
+			{token.RIGHT_CHEVRON, ">"},
+			{token.EOF, ""},
+		}
+
+		l := NewLexer(f, input)
+		for i, tt := range tests {
+			tok := l.NextToken()
+			assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d", i)
+			assert.Equal(t, tt.expectedLiteral, tok.Literal, "Token idx %d", i)
+		}
+	})
+
+	t.Run(`nested braces and literals`, func(t *testing.T) {
+		input := "<p>{map[string]int{\"}\": len(`{`)}['}']}</p>"
+		f := fset.AddFile("", fset.Base(), len(input))
+
+		l := NewLexer(f, input)
+		for range 3 {
+			l.NextToken()
+		}
+		tok := l.NextToken()
+		assert.Equal(t, token.GO_EXPRESSION, tok.Type)
+		assert.Equal(t, "map[string]int{\"}\": len(`{`)}['}']", tok.Literal)
+		assert.Equal(t, f.Pos(4), tok.Pos)
+	})
+
+	t.Run(`empty expression`, func(t *testing.T) {
+		input := `<p>{ }</p>`
+		f := fset.AddFile("", fset.Base(), len(input))
+
+		l := NewLexer(f, input)
+		for range 3 {
+			l.NextToken()
+		}
+		assert.Equal(t, token.ERROR, l.NextToken().Type)
+	})
+}
+
+func TestAttributeExpression(t *testing.T) {
+	input := `<a class={c.classes()} href="/">`
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LEFT_CHEVRON, "<"},
+		{token.IDENT, "a"},
+		{token.IDENT, "class"},
+		{token.ASSIGN, "="},
+		{token.GO_EXPRESSION, "c.classes()"},
+		{token.IDENT, "href"},
+		{token.ASSIGN, "="},
+		{token.QUOTE, `"`},
+		{token.TEXT, "/"},
+		{token.QUOTE, `"`},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(f, input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d", i)
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "Token idx %d", i)
+	}
+}
+
+func TestDirective(t *testing.T) {
+	input := `<input on:click={c.handleClick} bind:value={c.setText}/>`
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LEFT_CHEVRON, "<"},
+		{token.IDENT, "input"},
+		{token.ON, "on"},
+		{token.COLON, ":"},
+		{token.IDENT, "click"},
+		{token.ASSIGN, "="},
+		{token.GO_EXPRESSION, "c.handleClick"},
+		{token.IDENT, "bind"},
+		{token.COLON, ":"},
+		{token.IDENT, "value"},
+		{token.ASSIGN, "="},
+		{token.GO_EXPRESSION, "c.setText"},
+		{token.SLASH, "/"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(f, input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d", i)
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "Token idx %d", i)
+	}
+}
+
+func TestEmptyCodeBlock(t *testing.T) {
+	input := "---\n---\n<test/>"
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	tests := []token.TokenType{
+		token.CODE_FENCE,
+		token.CODE_FENCE,
+		token.TEXT,
+		token.LEFT_CHEVRON,
+		token.IDENT,
+		token.SLASH,
+		token.RIGHT_CHEVRON,
+		token.EOF,
+	}
+
+	l := NewLexer(f, input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt, tok.Type, "Token idx %d", i)
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `<div a=b =c><p>{}</p></div>`
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LEFT_CHEVRON, "<"},
+		{token.IDENT, "div"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.ERROR, `expected quote(") or '{' after attribute assign`},
+		{token.ERROR, "expected attribute name, got '='"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.LEFT_CHEVRON, "<"},
+		{token.IDENT, "p"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.ERROR, "empty expression"},
+		{token.LEFT_CHEVRON, "<"},
+		{token.SLASH, "/"},
+		{token.IDENT, "p"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.LEFT_CHEVRON, "<"},
+		{token.SLASH, "/"},
+		{token.IDENT, "div"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(f, input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d", i)
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "Token idx %d", i)
+	}
+}
+
+func TestTextWhiteSpace(t *testing.T) {
+	input := "<p>\n\t<b>a</b> <i>b</i>\n\tc {d}\n</p>"
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	texts := make([]string, 0)
+	l := NewLexer(f, input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.TEXT {
+			texts = append(texts, tok.Literal)
+		}
+	}
+	assert.Equal(t, []string{"\n\t", "a", " ", "b", "\n\tc ", "\n"}, texts, "expected white space to be kept")
+}
+
+func TestSpaceBeforeTagName(t *testing.T) {
+	input := "< p>x</p>< >"
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LEFT_CHEVRON, "<"},
+		{token.ERROR, "expected tag name after '<'"},
+		{token.IDENT, "p"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.TEXT, "x"},
+		{token.LEFT_CHEVRON, "<"},
+		{token.SLASH, "/"},
+		{token.IDENT, "p"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.LEFT_CHEVRON, "<"},
+		{token.ERROR, "expected tag name after '<'"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(f, input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d", i)
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "Token idx %d", i)
+	}
+}
+
+func TestIfBlock(t *testing.T) {
+	input := `{#if c.loading}<p/>{:else if len(c.items) == 0}empty{:else}{c.count}{/if}`
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.BLOCK_OPEN, "{#"},
+		{token.IF, "if"},
+		{token.GO_EXPRESSION, "c.loading"},
+		{token.RIGHT_BRACE, "}"},
+		{token.LEFT_CHEVRON, "<"},
+		{token.IDENT, "p"},
+		{token.SLASH, "/"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.BLOCK_CONTINUE, "{:"},
+		{token.ELSE, "else"},
+		{token.IF, "if"},
+		{token.GO_EXPRESSION, "len(c.items) == 0"},
+		{token.RIGHT_BRACE, "}"},
+		{token.TEXT, "empty"},
+		{token.BLOCK_CONTINUE, "{:"},
+		{token.ELSE, "else"},
+		{token.RIGHT_BRACE, "}"},
+		{token.GO_EXPRESSION, "c.count"},
+		{token.BLOCK_CLOSE, "{/"},
+		{token.IF, "if"},
+		{token.RIGHT_BRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(f, input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d", i)
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "Token idx %d", i)
+	}
+}
+
+func TestEachBlock(t *testing.T) {
+	input := `{#each c.items as item, i (item.ID)}<li/>{:else}none{/each}`
+	fset := source.NewFileSet()
+	f := fset.AddFile("", fset.Base(), len(input))
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.BLOCK_OPEN, "{#"},
+		{token.EACH, "each"},
+		{token.GO_EXPRESSION, "c.items as item, i (item.ID)"},
+		{token.RIGHT_BRACE, "}"},
+		{token.LEFT_CHEVRON, "<"},
+		{token.IDENT, "li"},
+		{token.SLASH, "/"},
+		{token.RIGHT_CHEVRON, ">"},
+		{token.BLOCK_CONTINUE, "{:"},
+		{token.ELSE, "else"},
+		{token.RIGHT_BRACE, "}"},
+		{token.TEXT, "none"},
+		{token.BLOCK_CLOSE, "{/"},
+		{token.EACH, "each"},
+		{token.RIGHT_BRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(f, input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.expectedType, tok.Type, "Token idx %d", i)
+		assert.Equal(t, tt.expectedLiteral, tok.Literal, "Token idx %d", i)
+	}
+}
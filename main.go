@@ -1,28 +1,36 @@
 package main
 
 import (
+	"context"
 	source "go/token"
-	"io"
-	"io/fs"
 	"os"
-	"path/filepath"
-	"strings"
+	"os/signal"
 
-	"github.com/tifye/flamingo/assert"
 	"github.com/tifye/flamingo/compiler"
+	"github.com/tifye/flamingo/parser"
 )
 
 func main() {
-	fset := source.NewFileSet()
-	compiler.CompileDir("main", fset, ".", func(fi fs.FileInfo) (io.WriteCloser, error) {
-		assert.Assert(!fi.IsDir(), "expected to be file")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-		dir := filepath.Dir(fi.Name())
-		name := strings.TrimSuffix(filepath.Base(fi.Name()), ".flamingo")
-		file, err := os.OpenFile(filepath.Join(dir, name+"_flamingo.go"), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-		if err != nil {
-			return nil, err
+	paths := os.Args[1:]
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	dirs := make([]compiler.Dir, len(paths))
+	for i, path := range paths {
+		dirs[i] = compiler.Dir{
+			Path: path,
+			FS:   os.DirFS(path),
+			Sink: compiler.NewDirSink(path),
 		}
-		return file, nil
-	})
+	}
+
+	fset := source.NewFileSet()
+	err := compiler.Build(ctx, fset, dirs, 0)
+	if err != nil {
+		parser.PrintError(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,340 @@
+package parser
+
+import (
+	"go/scanner"
+	source "go/token"
+	"slices"
+
+	"github.com/tifye/flamingo/assert"
+	"github.com/tifye/flamingo/ast"
+	"github.com/tifye/flamingo/token"
+)
+
+// parseBlock parses the block starting at curToken,
+// the opening of its first block tag.
+func (p *Parser) parseBlock() ast.RenderNode {
+	assert.Assert(p.isCurToken(token.BLOCK_OPEN), "expected curToken to be BLOCK_OPEN")
+
+	from := p.curToken.Pos
+	switch {
+	case p.tryPeek(token.IF):
+		return p.parseIfBlock(from)
+	case p.tryPeek(token.EACH):
+		return p.parseEachBlock(from)
+	default:
+		p.errorf(p.peekToken.Pos, "unknown block %s, expected if or each", p.peekToken.Literal)
+		p.syncBlockTag()
+		return p.badNode(from)
+	}
+}
+
+// parseIfBlock parses the if block whose opening tag starts at
+// from, with curToken being its keyword. Blocks which are not
+// closed are returned with the branches parsed so far.
+func (p *Parser) parseIfBlock(from source.Pos) *ast.IfBlock {
+	assert.Assert(p.isCurToken(token.IF), "expected curToken to be IF")
+
+	block := &ast.IfBlock{
+		Open: from,
+		Cond: p.parseBlockCond("if"),
+		Then: &ast.Fragment{Nodes: make([]ast.RenderNode, 0)},
+	}
+
+	p.blocks = append(p.blocks, "if")
+	defer func() {
+		p.blocks = p.blocks[:len(p.blocks)-1]
+	}()
+
+	branch, nodes, inElse := block, &block.Then.Nodes, false
+	for {
+		*nodes = append(*nodes, p.parseChildren()...)
+
+		if !p.isCurToken(token.BLOCK_CONTINUE) {
+			if end, ok := p.parseBlockEnd("if", from, nodes); ok {
+				setIfClose(block, end)
+				return block
+			}
+			continue
+		}
+
+		tagFrom := p.curToken.Pos
+		if !p.expectPeek(token.ELSE) {
+			p.syncBlockTag()
+			continue
+		}
+		if inElse {
+			p.errorf(tagFrom, "unexpected {:else} after {:else}")
+			p.syncBlockTag()
+			continue
+		}
+
+		if p.tryPeek(token.IF) {
+			elseIf := &ast.IfBlock{
+				Open: tagFrom,
+				Cond: p.parseBlockCond("else if"),
+				Then: &ast.Fragment{Nodes: make([]ast.RenderNode, 0)},
+			}
+			branch.Else = elseIf
+			branch, nodes = elseIf, &elseIf.Then.Nodes
+			continue
+		}
+
+		p.expectBlockEnd()
+		els := &ast.Fragment{Nodes: make([]ast.RenderNode, 0)}
+		branch.Else = els
+		nodes, inElse = &els.Nodes, true
+	}
+}
+
+// setIfClose sets the Close of block and of
+// the else if blocks continuing it.
+func setIfClose(block *ast.IfBlock, pos source.Pos) {
+	for b := block; b != nil; {
+		b.Close = pos
+		b, _ = b.Else.(*ast.IfBlock)
+	}
+}
+
+// parseBlockCond parses the condition following the
+// keywords of a block tag, up to and including the
+// closing brace of the tag.
+func (p *Parser) parseBlockCond(keywords string) *ast.Expr {
+	if !p.tryPeek(token.GO_EXPRESSION) {
+		p.errorf(p.peekToken.Pos, "missing condition in {%s}", keywords)
+		p.syncBlockTag()
+		return nil
+	}
+
+	cond := p.parseExpr()
+	p.expectBlockEnd()
+	return cond
+}
+
+// parseEachBlock parses the each block whose opening tag starts
+// at from, with curToken being its keyword. Blocks which are not
+// closed are returned with the branches parsed so far.
+func (p *Parser) parseEachBlock(from source.Pos) *ast.EachBlock {
+	assert.Assert(p.isCurToken(token.EACH), "expected curToken to be EACH")
+
+	block := &ast.EachBlock{
+		Open: from,
+		Body: &ast.Fragment{Nodes: make([]ast.RenderNode, 0)},
+	}
+
+	if p.tryPeek(token.GO_EXPRESSION) {
+		p.parseEachHeader(block)
+		p.expectBlockEnd()
+	} else {
+		p.errorf(p.peekToken.Pos, "missing expression in {#each}")
+		p.syncBlockTag()
+	}
+
+	p.blocks = append(p.blocks, "each")
+	defer func() {
+		p.blocks = p.blocks[:len(p.blocks)-1]
+	}()
+
+	nodes := &block.Body.Nodes
+	for {
+		*nodes = append(*nodes, p.parseChildren()...)
+
+		if !p.isCurToken(token.BLOCK_CONTINUE) {
+			if end, ok := p.parseBlockEnd("each", from, nodes); ok {
+				block.Close = end
+				return block
+			}
+			continue
+		}
+
+		tagFrom := p.curToken.Pos
+		if !p.expectPeek(token.ELSE) {
+			p.syncBlockTag()
+			continue
+		}
+		if p.isPeekToken(token.IF) {
+			p.errorf(tagFrom, "unexpected {:else if} in each block")
+			p.syncBlockTag()
+			continue
+		}
+		if block.Else != nil {
+			p.errorf(tagFrom, "unexpected {:else} after {:else}")
+			p.syncBlockTag()
+			continue
+		}
+
+		p.expectBlockEnd()
+		block.Else = &ast.Fragment{Nodes: make([]ast.RenderNode, 0)}
+		nodes = &block.Else.Nodes
+	}
+}
+
+// parseEachHeader parses the GO_EXPRESSION curToken as the header
+// of block, Expr as Value, Index (Key), where all but Expr are
+// optional. The header is scanned as Go, so that Expr and Key may
+// hold any expression not containing the identifier as.
+func (p *Parser) parseEachHeader(block *ast.EachBlock) {
+	src, base := p.curToken.Literal, p.curToken.Pos
+
+	// Positions reported by the scanner are offsets into src
+	file := source.NewFileSet().AddFile("", 1, len(src))
+	pos := func(sp source.Pos) source.Pos {
+		return base + source.Pos(file.Offset(sp))
+	}
+
+	var s scanner.Scanner
+	s.Init(file, []byte(src), nil, 0) // syntax errors are reported by the Go compiler
+	next := func() (source.Pos, source.Token, string) {
+		for {
+			sp, tok, lit := s.Scan()
+			// Skip semicolons inserted at the end of the header
+			if tok == source.SEMICOLON && lit == "\n" {
+				continue
+			}
+			return pos(sp), tok, lit
+		}
+	}
+
+	depth := 0
+	var at source.Pos
+	var tok source.Token
+	var lit string
+	for {
+		at, tok, lit = next()
+		switch tok {
+		case source.LPAREN, source.LBRACK, source.LBRACE:
+			depth++
+		case source.RPAREN, source.RBRACK, source.RBRACE:
+			depth--
+		}
+		if tok == source.EOF || tok == source.IDENT && lit == "as" && depth == 0 {
+			break
+		}
+	}
+
+	code := src[:int(at-base)]
+	block.Expr = &ast.Expr{
+		Lbrace: base - 1,
+		Code:   code,
+		Rbrace: at,
+	}
+	if tok == source.EOF {
+		return
+	}
+
+	if at, tok, lit = next(); tok != source.IDENT {
+		p.errorf(at, "expected variable name after as in {#each}, got %s", tok)
+		return
+	}
+	block.Value = &ast.Ident{Position: at, Name: lit}
+
+	at, tok, _ = next()
+	if tok == source.COMMA {
+		if at, tok, lit = next(); tok != source.IDENT {
+			p.errorf(at, "expected index variable name after ',' in {#each}, got %s", tok)
+			return
+		}
+		block.Index = &ast.Ident{Position: at, Name: lit}
+		at, tok, _ = next()
+	}
+
+	if tok == source.LPAREN {
+		lparen := at
+		for depth = 1; depth > 0; {
+			at, tok, _ = next()
+			switch tok {
+			case source.LPAREN:
+				depth++
+			case source.RPAREN:
+				depth--
+			case source.EOF:
+				p.errorf(at, "expected ')' to close key in {#each}")
+				return
+			}
+		}
+		block.Key = &ast.Expr{
+			Lbrace: lparen,
+			Code:   src[int(lparen-base)+1 : int(at-base)],
+			Rbrace: at,
+		}
+		at, tok, _ = next()
+	}
+
+	if tok != source.EOF {
+		p.errorf(at, "unexpected %s in {#each}", tok)
+	}
+}
+
+// parseBlockEnd handles curToken ending the body of the block
+// named keyword, opened at from, other than a block tag
+// continuing it. It reports whether the block is ended and the
+// position ending it. Closing tags without an element to close
+// are reported and appended to nodes.
+func (p *Parser) parseBlockEnd(keyword string, from source.Pos, nodes *[]ast.RenderNode) (source.Pos, bool) {
+	switch {
+	case p.closing != nil:
+		// One of the nodes found the closing tag of an element enclosing us
+		p.errorf(from, "unclosed %s block", keyword)
+		return p.closing.LeftChevron, true
+
+	case p.isCurToken(token.EOF):
+		p.errorf(from, "unclosed %s block", keyword)
+		return p.curToken.Pos, true
+
+	case p.isCurToken(token.LEFT_CHEVRON):
+		if len(p.open) > 0 {
+			// The closing tag belongs to an element enclosing us
+			p.errorf(from, "unclosed %s block", keyword)
+			p.held = true
+			return p.curToken.Pos, true
+		}
+
+		tagFrom := p.curToken.Pos
+		if tag := p.parseCloseTag(); tag != nil {
+			p.errorf(tag.Name.Pos(), "unexpected closing tag %s", tag.Name.Name)
+		}
+		*nodes = append(*nodes, p.badNode(tagFrom))
+		return source.NoPos, false
+	}
+
+	assert.Assert(p.isCurToken(token.BLOCK_CLOSE), "expected curToken to be BLOCK_CLOSE")
+
+	if p.peekToken.Literal == keyword {
+		p.nextToken()
+		p.expectBlockEnd()
+		return p.curToken.Pos, true
+	}
+
+	if slices.Contains(p.blocks[:len(p.blocks)-1], p.peekToken.Literal) {
+		// The tag closes a block enclosing us
+		p.errorf(from, "unclosed %s block", keyword)
+		p.held = true
+		return p.curToken.Pos, true
+	}
+
+	p.errorf(p.curToken.Pos, "unexpected {/%s}, expected {/%s}", p.peekToken.Literal, keyword)
+	p.syncBlockTag()
+	return source.NoPos, false
+}
+
+// expectBlockEnd expects the closing brace of a block tag,
+// skipping the rest of the tag if it is malformed.
+func (p *Parser) expectBlockEnd() {
+	if !p.expectPeek(token.RIGHT_BRACE) {
+		p.syncBlockTag()
+	}
+}
+
+// syncBlockTag skips the tokens of a malformed block tag up to
+// and including its '}', reporting whether the tag was ended.
+func (p *Parser) syncBlockTag() bool {
+	for !p.isPeekToken(token.RIGHT_BRACE) &&
+		!p.isPeekToken(token.LEFT_CHEVRON) &&
+		!p.isPeekToken(token.BLOCK_OPEN) &&
+		!p.isPeekToken(token.BLOCK_CONTINUE) &&
+		!p.isPeekToken(token.BLOCK_CLOSE) &&
+		!p.isPeekToken(token.EOF) {
+		p.nextToken()
+	}
+
+	return p.tryPeek(token.RIGHT_BRACE)
+}
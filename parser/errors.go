@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	source "go/token"
+	"io"
+	"slices"
+)
+
+// An Error is a syntax error found in a Flamingo component.
+// Pos, if valid, points to the start of the offending token.
+type Error struct {
+	Pos source.Position
+	Msg string
+}
+
+// Error implements the error interface, formatting the
+// error as file:line:column: message.
+func (e Error) Error() string {
+	if e.Pos.Filename != "" || e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Msg
+	}
+	return e.Msg
+}
+
+// ErrorList is a list of *Errors. The zero value
+// for an ErrorList is an empty ErrorList ready to use.
+type ErrorList []*Error
+
+// Add adds an Error with the given position and message to an ErrorList.
+func (p *ErrorList) Add(pos source.Position, msg string) {
+	*p = append(*p, &Error{Pos: pos, Msg: msg})
+}
+
+// Reset resets an ErrorList to no errors.
+func (p *ErrorList) Reset() { *p = (*p)[0:0] }
+
+// ErrorList implements the sort Interface.
+func (p ErrorList) Len() int      { return len(p) }
+func (p ErrorList) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+func (p ErrorList) Less(i, j int) bool {
+	return compareError(p[i], p[j]) < 0
+}
+
+// compareError orders errors by filename, line, column and message.
+func compareError(a, b *Error) int {
+	e, f := &a.Pos, &b.Pos
+	if e.Filename != f.Filename {
+		if e.Filename < f.Filename {
+			return -1
+		}
+		return 1
+	}
+	if e.Line != f.Line {
+		return e.Line - f.Line
+	}
+	if e.Column != f.Column {
+		return e.Column - f.Column
+	}
+	if a.Msg < b.Msg {
+		return -1
+	}
+	if a.Msg > b.Msg {
+		return 1
+	}
+	return 0
+}
+
+// Sort sorts an ErrorList by position and then message.
+func (p ErrorList) Sort() {
+	slices.SortStableFunc(p, compareError)
+}
+
+// RemoveMultiples sorts an ErrorList and removes duplicate
+// entries, that is errors with the same position and message.
+// Unlike go/scanner only exact duplicates are removed, as one
+// line of markup commonly holds several independent mistakes.
+func (p *ErrorList) RemoveMultiples() {
+	p.Sort()
+	*p = slices.CompactFunc(*p, func(a, b *Error) bool {
+		return compareError(a, b) == 0
+	})
+}
+
+// An ErrorList implements the error interface.
+func (p ErrorList) Error() string {
+	switch len(p) {
+	case 0:
+		return "no errors"
+	case 1:
+		return p[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", p[0], len(p)-1)
+}
+
+// Err returns an error equivalent to this error list.
+// If the list is empty, Err returns nil.
+func (p ErrorList) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return p
+}
+
+// PrintError is a utility function that prints a list of errors to w,
+// one error per line, if the err parameter is an ErrorList. Otherwise
+// it prints the err string.
+func PrintError(w io.Writer, err error) {
+	var list ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			fmt.Fprintf(w, "%s\n", e)
+		}
+	} else if err != nil {
+		fmt.Fprintf(w, "%s\n", err)
+	}
+}
@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"errors"
+	source "go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/ast"
+)
+
+func TestErrorPositions(t *testing.T) {
+	t.Run("parser error", func(t *testing.T) {
+		input := "<div>\n    <span></div>\n</div>"
+		_, err := ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+
+		var list ErrorList
+		require.True(t, errors.As(err, &list), "expected an ErrorList, got %T", err)
+		require.NotEmpty(t, list)
+		assert.Equal(t, "Mino.flamingo:2:13: unexpected closing tag div, expected span", list[0].Error())
+	})
+
+	t.Run("lexer error", func(t *testing.T) {
+		input := "<div>\n  <p>{}</p>\n</div>"
+		_, err := ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+
+		var list ErrorList
+		require.True(t, errors.As(err, &list), "expected an ErrorList, got %T", err)
+		require.NotEmpty(t, list)
+		assert.Equal(t, "Mino.flamingo:2:7: empty expression", list[0].Error())
+	})
+}
+
+func TestErrorList(t *testing.T) {
+	var list ErrorList
+	list.Add(source.Position{Filename: "b.flamingo", Line: 1, Column: 1}, "meep")
+	list.Add(source.Position{Filename: "a.flamingo", Line: 3, Column: 2}, "mino")
+	list.Add(source.Position{Filename: "a.flamingo", Line: 3, Column: 1}, "mino")
+	list.Add(source.Position{Filename: "a.flamingo", Line: 3, Column: 1}, "mino")
+	list.Add(source.Position{Filename: "a.flamingo", Line: 3, Column: 1}, "izu")
+
+	list.RemoveMultiples()
+	require.Len(t, list, 4)
+	assert.Equal(t, "a.flamingo:3:1: izu (and 3 more errors)", list.Error())
+
+	var sb strings.Builder
+	PrintError(&sb, list)
+	assert.Equal(t, "a.flamingo:3:1: izu\na.flamingo:3:1: mino\na.flamingo:3:2: mino\nb.flamingo:1:1: meep\n", sb.String())
+
+	list.Reset()
+	assert.NoError(t, list.Err())
+}
+
+func TestMalformedInput(t *testing.T) {
+	inputs := []string{
+		``,
+		`<>`,
+		`< div>`,
+		`<div =x>`,
+		`<div on:>`,
+		`<div :x>`,
+		`<div></span>`,
+		`meep <div>`,
+		"---\nfunc _() {}",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				_, _ = ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+			})
+			assert.NotPanics(t, func() {
+				_, err := ParseElement(input)
+				assert.Error(t, err)
+			})
+		})
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	t.Run("independent errors", func(t *testing.T) {
+		input := "<div a=b>\n  <p>{}</p>\n  <span on:>x</span>\n  <ul><li></ul>\n</div>"
+		_, err := ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+
+		var list ErrorList
+		require.True(t, errors.As(err, &list), "expected an ErrorList, got %T", err)
+		expected := []string{
+			`Mino.flamingo:1:8: expected quote(") or '{' after attribute assign`,
+			"Mino.flamingo:2:7: empty expression",
+			"Mino.flamingo:3:12: expected name after ':' in attribute name",
+			"Mino.flamingo:4:13: unexpected closing tag ul, expected li",
+		}
+		actual := make([]string, 0, len(list))
+		for _, e := range list {
+			actual = append(actual, e.Error())
+		}
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("partial tree", func(t *testing.T) {
+		input := "<div>\n  <>\n  <p>meep</p>\n  </span>\n</div>"
+		file, err := ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+		require.Error(t, err)
+		require.NotNil(t, file)
+		require.Len(t, file.Fragment.Nodes, 1)
+
+		div, ok := file.Fragment.Nodes[0].(*ast.Element)
+		require.True(t, ok, "expected *ast.Element, got %T", file.Fragment.Nodes[0])
+		require.Len(t, div.Nodes, 3)
+		assert.IsType(t, &ast.BadNode{}, div.Nodes[0])
+		assert.IsType(t, &ast.Element{}, div.Nodes[1])
+		assert.IsType(t, &ast.BadNode{}, div.Nodes[2])
+		assert.Equal(t, "meep", div.Nodes[1].(*ast.Element).Nodes[0].(*ast.Text).Literal)
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		input := "<div>\n  {#if a}<p>x{:else}y{/if}\n  {#if}z{/if}\n  {:else}\n</div>"
+		file, err := ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+
+		var list ErrorList
+		require.True(t, errors.As(err, &list), "expected an ErrorList, got %T", err)
+		expected := []string{
+			"Mino.flamingo:2:11: unclosed element p",
+			"Mino.flamingo:3:7: missing condition in {if}",
+			"Mino.flamingo:4:3: unexpected {:else}, no block is open",
+		}
+		actual := make([]string, 0, len(list))
+		for _, e := range list {
+			actual = append(actual, e.Error())
+		}
+		assert.Equal(t, expected, actual)
+
+		div := file.Fragment.Nodes[0].(*ast.Element)
+		require.Len(t, div.Nodes, 3)
+		block := div.Nodes[0].(*ast.IfBlock)
+		assert.IsType(t, &ast.Fragment{}, block.Else)
+	})
+
+	t.Run("space before tag name", func(t *testing.T) {
+		input := "<div>\n< p>x</p>\n</div>"
+		file, err := ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+		require.EqualError(t, err, "Mino.flamingo:2:2: expected tag name after '<'")
+
+		div := file.Fragment.Nodes[0].(*ast.Element)
+		require.Len(t, div.Nodes, 1)
+		assert.Equal(t, "p", div.Nodes[0].(*ast.Element).Name.Name, "expected the closing tag to match the malformed tag")
+	})
+
+	t.Run("unclosed element", func(t *testing.T) {
+		input := "<div>\n  <p>meep</p>\n"
+		file, err := ParseFile(source.NewFileSet(), "Mino.flamingo", input)
+		require.EqualError(t, err, "Mino.flamingo:1:2: unclosed element div")
+		require.Len(t, file.Fragment.Nodes, 1)
+		assert.Len(t, file.Fragment.Nodes[0].(*ast.Element).Nodes, 1)
+	})
+}
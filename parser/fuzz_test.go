@@ -0,0 +1,33 @@
+package parser
+
+import (
+	source "go/token"
+	"testing"
+)
+
+func FuzzParseFile(f *testing.F) {
+	seeds := []string{
+		``,
+		`<div class="bg-rose-500">mino</div>`,
+		"---\nfunc _() {}\n---\n<test></test>",
+		`<span>Hello {c.Name}</span>`,
+		`<input on:click={c.handleClick} type="text" bind:value={c.setText}/>`,
+		`<div><meep><div></div></meep><mino></mino></div>`,
+		`< div>`,
+		`<>`,
+		`</div>`,
+		`<div><span></div>`,
+		`<input meep:click="x"/>`,
+		`<div>{#if c.a}<p/>{:else if c.b}b{:else}{c.c}{/if}</div>`,
+		`<ul>{#each c.items as item, i (item.ID)}<li>{item}</li>{:else}none{/each}</ul>`,
+		`<div><slot name="a" class="b"><p/></slot><slot name={c.x}/></div>`,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		_, _ = ParseFile(source.NewFileSet(), "fuzz.flamingo", input)
+		_, _ = ParseElement(input)
+	})
+}
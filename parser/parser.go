@@ -7,6 +7,7 @@ import (
 	source "go/token"
 	"io"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/tifye/flamingo/assert"
@@ -25,11 +26,14 @@ func ParseElement(src any) (*ast.Element, error) {
 	file := fset.AddFile("", fset.Base(), len(input))
 	l := lexer.NewLexer(file, string(input)).WithState(lexer.LexTagStart)
 	p := NewParser(l)
-	el := p.parseElement()
-	if n := len(p.Errors()); n > 0 {
-		return el, errors.New(strings.Join(p.Errors(), "; "))
+	var el *ast.Element
+	if p.isCurToken(token.LEFT_CHEVRON) && !p.isPeekToken(token.SLASH) {
+		el, _ = p.parseElement().(*ast.Element)
+	} else if len(p.errors) == 0 {
+		p.errorf(p.curToken.Pos, "expected element, got %s", p.curToken.Type)
 	}
-	return el, nil
+	p.errors.RemoveMultiples()
+	return el, p.errors.Err()
 }
 
 func ParseFile(fset *source.FileSet, filename string, src any) (*ast.File, error) {
@@ -38,15 +42,15 @@ func ParseFile(fset *source.FileSet, filename string, src any) (*ast.File, error
 		return nil, fmt.Errorf("reading source: %s", err)
 	}
 
-	file := fset.AddFile(filename, fset.Base(), len(input))
+	// The base is taken by AddFile so that files can
+	// be parsed into fset concurrently
+	file := fset.AddFile(filename, -1, len(input))
 	l := lexer.NewLexer(file, string(input))
 	p := NewParser(l)
 
 	fileNode := p.Parse()
-	if len(p.errors) == 0 {
-		return fileNode, nil
-	}
-	return fileNode, fmt.Errorf("%v", p.errors)
+	p.errors.RemoveMultiples()
+	return fileNode, p.errors.Err()
 }
 
 func readSource(filename string, src any) ([]byte, error) {
@@ -72,28 +76,80 @@ func readSource(filename string, src any) ([]byte, error) {
 
 type Parser struct {
 	l         *lexer.Lexer
+	file      *source.File
 	curToken  token.Token
 	peekToken token.Token
-	errors    []string
+	errors    ErrorList
+
+	// syncing is set after a syntax error until the next sync
+	// point, a tag chevron, block tag brace or code fence,
+	// suppressing the errors caused by the tokens skipped in
+	// between.
+	syncing bool
+
+	// held is set when curToken ends the node being parsed but
+	// belongs to an enclosing one, leaving it as curToken for
+	// the next call to nextToken.
+	held bool
+
+	// open holds the names of the elements being parsed,
+	// innermost last.
+	open []string
+
+	// blocks holds the keywords of the blocks being parsed,
+	// innermost last.
+	blocks []string
+
+	// closing is set to the closing tag of an enclosing element
+	// when it is encountered while parsing one of its children.
+	closing *closeTag
+}
+
+// A closeTag is a closing tag whose
+// element has not been closed yet.
+type closeTag struct {
+	LeftChevron source.Pos
+	Name        *ast.Ident
 }
 
 func NewParser(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		file:   l.File(),
+		errors: ErrorList{},
 	}
 	p.nextToken() // sets peekToken
 	p.nextToken() // sets curToken
 	return p
 }
 
-func (p *Parser) Errors() []string {
+// Errors returns the errors encountered so far, in the
+// order they were found.
+func (p *Parser) Errors() ErrorList {
 	return p.errors
 }
 
 func (p *Parser) nextToken() {
+	if p.held {
+		p.held = false
+		return
+	}
+
 	p.curToken = p.peekToken
+	switch p.curToken.Type {
+	case token.LEFT_CHEVRON, token.RIGHT_CHEVRON, token.CODE_FENCE,
+		token.BLOCK_OPEN, token.BLOCK_CONTINUE, token.BLOCK_CLOSE, token.RIGHT_BRACE:
+		p.syncing = false
+	}
+
+	// The lexer recovers from its errors, so they are
+	// reported and skipped rather than ending the parse
 	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == token.ERROR {
+		p.errors.Add(p.file.Position(p.peekToken.Pos), p.peekToken.Literal)
+		p.syncing = true
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 func (p *Parser) Parse() *ast.File {
@@ -104,6 +160,7 @@ func (p *Parser) Parse() *ast.File {
 
 	if p.isCurToken(token.CODE_FENCE) {
 		root.CodeBlock = p.parseCodeBlock()
+		p.nextToken()
 	}
 
 	for !p.isCurToken(token.EOF) {
@@ -112,12 +169,9 @@ func (p *Parser) Parse() *ast.File {
 			root.Fragment.Nodes = append(root.Fragment.Nodes, el)
 		}
 
-		if p.isPeekToken(token.EOF) {
-			break
-		}
-
 		p.nextToken()
 	}
+	root.Fragment.Nodes = spaceTexts(root.Fragment.Nodes)
 
 	return root
 }
@@ -129,12 +183,11 @@ func (p *Parser) parseCodeBlock() *ast.CodeBlock {
 		TopFence: p.curToken.Pos,
 	}
 
-	if !p.expectPeek(token.GO_CODE) {
-		return nil
+	if p.tryPeek(token.GO_CODE) {
+		codeBlock.CodePos = p.curToken.Pos
+		codeBlock.Code = p.curToken.Literal
 	}
 
-	codeBlock.Code = p.curToken.Literal
-
 	if !p.expectPeek(token.CODE_FENCE) {
 		return nil
 	}
@@ -143,99 +196,337 @@ func (p *Parser) parseCodeBlock() *ast.CodeBlock {
 	return codeBlock
 }
 
-// Tries to parse curToken to an Element
-// otherwise returns nil.
-//
-// Uses peekToken incases of '<' specifically
-// looking for '/' in which case it also returns nil.
+// parseRenderNode parses the node starting at curToken.
+// Closing and block tags, which at this point have no
+// element or block to close, and stray tokens are reported
+// and returned as an *ast.BadNode.
 func (p *Parser) parseRenderNode() ast.RenderNode {
 	switch p.curToken.Type {
 	case token.TEXT:
 		return &ast.Text{
 			Position: p.curToken.Pos,
-			Literal:  strings.TrimSpace(p.curToken.Literal),
+			Literal:  p.curToken.Literal,
 		}
+	case token.GO_EXPRESSION:
+		return p.parseExpr()
 	case token.LEFT_CHEVRON:
 		if p.isPeekToken(token.SLASH) {
-			return nil
+			from := p.curToken.Pos
+			if tag := p.parseCloseTag(); tag != nil {
+				p.errorf(tag.Name.Pos(), "unexpected closing tag %s", tag.Name.Name)
+			}
+			return p.badNode(from)
 		}
-		return p.parseElement()
+		node := p.parseElement()
+		if el, ok := node.(*ast.Element); ok && el.Name.Name == ast.SlotTag {
+			return p.slotOf(el)
+		}
+		return node
+	case token.BLOCK_OPEN:
+		return p.parseBlock()
+	case token.BLOCK_CONTINUE, token.BLOCK_CLOSE:
+		from := p.curToken.Pos
+		p.errorf(from, "unexpected %s%s}, no block is open", p.curToken.Literal, p.peekToken.Literal)
+		p.syncBlockTag()
+		return p.badNode(from)
 	default:
-		return nil
+		from := p.curToken.Pos
+		p.errorf(from, "unexpected %s", p.curToken.Type)
+		return p.badNode(from)
+	}
+}
+
+// spaceTexts collapses the white space of the texts of nodes, see
+// collapseSpace. Texts of white space alone breaking a line, such as
+// that indenting tags, are dropped at the start and the end of nodes,
+// next to the tags of their parent element or block, and next to the
+// nodes breaking lines, as HTML renders no space there. Between
+// inline nodes they are kept as the space separating them.
+func spaceTexts(nodes []ast.RenderNode) []ast.RenderNode {
+	spaced := make([]ast.RenderNode, 0, len(nodes))
+	for i, n := range nodes {
+		if text, ok := n.(*ast.Text); ok {
+			lineSpace := strings.TrimLeft(text.Literal, " \t\r\n") == "" && strings.Contains(text.Literal, "\n")
+			if lineSpace && (i == 0 || i == len(nodes)-1 || breaksLine(nodes[i-1]) || breaksLine(nodes[i+1])) {
+				continue
+			}
+			text.Literal = collapseSpace(text.Literal)
+		}
+		spaced = append(spaced, n)
+	}
+	return spaced
+}
+
+// breaksLine reports whether n is laid out on lines of its own, as
+// block-level elements are, or is delimited by tags of its own, as
+// blocks and slots are, like the tags of a parent element.
+func breaksLine(n ast.RenderNode) bool {
+	switch n := n.(type) {
+	case *ast.Element:
+		return blockTags[n.Name.Name]
+	case *ast.IfBlock, *ast.EachBlock, *ast.Slot, *ast.BadNode:
+		return true
+	}
+	return false
+}
+
+// blockTags are the tags of the block-level elements and of those
+// which are not rendered, around which HTML renders no space.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "base": true, "blockquote": true,
+	"body": true, "caption": true, "col": true, "colgroup": true, "dd": true,
+	"details": true, "dialog": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"head": true, "header": true, "hgroup": true, "hr": true, "html": true,
+	"li": true, "link": true, "main": true, "menu": true, "meta": true,
+	"nav": true, "noscript": true, "ol": true, "optgroup": true, "option": true,
+	"p": true, "pre": true, "script": true, "section": true, "style": true,
+	"summary": true, "table": true, "tbody": true, "td": true, "template": true,
+	"tfoot": true, "th": true, "thead": true, "title": true, "tr": true, "ul": true,
+}
+
+// collapseSpace replaces each run of white space in text with
+// a single space, keeping the space separating the text from the
+// nodes around it as HTML renders it.
+func collapseSpace(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
 	}
+	return b.String()
 }
 
-func (p *Parser) parseElement() (el *ast.Element) {
+// parseElement parses the element starting at curToken. Elements
+// with a malformed opening tag are skipped up to the end of the tag
+// and returned as an *ast.BadNode, elements which are not properly
+// closed are returned with the children parsed so far.
+func (p *Parser) parseElement() ast.RenderNode {
 	assert.Assert(p.isCurToken(token.LEFT_CHEVRON), "expected left chevron")
-	assert.Assert(p.isPeekToken(token.IDENT), "expected next token to be an identifier")
 
-	if !p.tryPeek(token.IDENT) {
-		return nil
+	from := p.curToken.Pos
+	if !p.expectPeek(token.IDENT) {
+		p.syncTag()
+		return p.badNode(from)
 	}
 
 	element := &ast.Element{
-		LeftChevron: p.curToken.Pos - 1,
+		LeftChevron: from,
 		Name: &ast.Ident{
 			Position: p.curToken.Pos,
 			Name:     p.curToken.Literal,
 		},
-		Attrs: make([]*ast.Attribute, 0),
-		Nodes: make([]ast.RenderNode, 0),
+		Attrs:      make([]*ast.Attribute, 0),
+		Directives: make([]*ast.Directive, 0),
+		Nodes:      make([]ast.RenderNode, 0),
 	}
-	defer func() {
-		if el != nil {
-			assert.Assert(element.RightChevron.IsValid(), "expected right chevron location to be set")
+
+	for p.tryPeek(token.IDENT) || p.tryPeek(token.ON) {
+		if p.isPeekToken(token.COLON) {
+			dir := p.parseDirective()
+			if dir != nil {
+				element.Directives = append(element.Directives, dir)
+			}
+			continue
 		}
-	}()
 
-	for p.tryPeek(token.IDENT) {
 		attr := p.parseAttribute()
 		if attr != nil {
 			element.Attrs = append(element.Attrs, attr)
 		}
 	}
 
-	if p.tryPeek(token.SLASH) {
-		if !p.expectPeek(token.RIGHT_CHEVRON) {
-			return nil
+	selfClosing := p.tryPeek(token.SLASH)
+	if !p.expectPeek(token.RIGHT_CHEVRON) {
+		var ok bool
+		if ok, selfClosing = p.syncTag(); !ok {
+			return p.badNode(from)
 		}
+	}
 
+	if selfClosing {
 		element.RightChevron = p.curToken.Pos
 		return element
 	}
 
-	assert.Assert(p.isPeekToken(token.RIGHT_CHEVRON), "expected next token to be a right chevron but got ")
-	_ = p.expectPeek(token.RIGHT_CHEVRON)
+	name := element.Name.Name
+	p.open = append(p.open, name)
+	defer func() {
+		p.open = p.open[:len(p.open)-1]
+	}()
 
 	for {
-		p.nextToken()
-		el := p.parseRenderNode()
-		if el == nil {
-			break
+		element.Nodes = append(element.Nodes, p.parseChildren()...)
+
+		// One of the children found our closing tag
+		// or that of an element enclosing us
+		if tag := p.closing; tag != nil {
+			if tag.Name.Name == name {
+				p.closing = nil
+			}
+			element.RightChevron = tag.LeftChevron
+			return element
+		}
+
+		switch p.curToken.Type {
+		case token.EOF:
+			p.errorf(element.Name.Pos(), "unclosed element %s", name)
+			element.RightChevron = p.curToken.Pos
+			return element
+		case token.BLOCK_CONTINUE, token.BLOCK_CLOSE:
+			// The tag belongs to a block enclosing us
+			p.errorf(element.Name.Pos(), "unclosed element %s", name)
+			element.RightChevron = p.curToken.Pos
+			p.held = true
+			return element
+		}
+
+		from := p.curToken.Pos
+		tag := p.parseCloseTag()
+		switch {
+		case tag == nil:
+			element.Nodes = append(element.Nodes, p.badNode(from))
+		case tag.Name.Name == name:
+			element.RightChevron = p.curToken.Pos
+			return element
+		case slices.Contains(p.open, tag.Name.Name):
+			// Assume the element was meant to be closed
+			// and let the enclosing element be closed
+			p.errorf(tag.Name.Pos(), "unexpected closing tag %s, expected %s", tag.Name.Name, name)
+			p.closing = tag
+			element.RightChevron = tag.LeftChevron
+			return element
+		default:
+			p.errorf(tag.Name.Pos(), "unexpected closing tag %s, expected %s", tag.Name.Name, name)
+			element.Nodes = append(element.Nodes, p.badNode(from))
 		}
-		element.Nodes = append(element.Nodes, el)
 	}
+}
 
-	if !p.expectPeek(token.SLASH) {
-		return nil
+// slotOf returns the slot declared by the element el,
+// reporting attributes other than its name.
+func (p *Parser) slotOf(el *ast.Element) *ast.Slot {
+	slot := &ast.Slot{
+		LeftChevron:  el.LeftChevron,
+		RightChevron: el.RightChevron,
+		Fallback:     &ast.Fragment{Nodes: el.Nodes},
+	}
+
+	for _, attr := range el.Attrs {
+		switch {
+		case attr.Name.Name != "name":
+			p.errorf(attr.Pos(), "unexpected attribute %s on slot, expected name", attr.Name.Name)
+		case attr.ValueExpr != nil:
+			p.errorf(attr.ValueExpr.Pos(), "slot name must be a string literal")
+		case slot.Name != "":
+			p.errorf(attr.Pos(), "slot name set more than once")
+		case attr.ValueLiteral == "":
+			p.errorf(attr.Pos(), "slot name must not be empty")
+		default:
+			slot.Name = attr.ValueLiteral
+		}
 	}
+	for _, dir := range el.Directives {
+		p.errorf(dir.Pos(), "unexpected directive %s:%s on slot", dir.Kind.Name, dir.Name.Name)
+	}
+
+	return slot
+}
+
+// parseChildren parses render nodes up to the token ending the
+// enclosing element or block, which is left as curToken: a closing
+// tag, a block tag continuing or closing a block, or EOF. Parsing
+// also stops once one of the nodes sets p.closing.
+func (p *Parser) parseChildren() []ast.RenderNode {
+	nodes := make([]ast.RenderNode, 0)
+	for {
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.EOF:
+			return spaceTexts(nodes)
+		case token.LEFT_CHEVRON:
+			if p.isPeekToken(token.SLASH) {
+				return spaceTexts(nodes)
+			}
+		case token.BLOCK_CONTINUE, token.BLOCK_CLOSE:
+			if len(p.blocks) > 0 {
+				return spaceTexts(nodes)
+			}
+		}
+
+		if node := p.parseRenderNode(); node != nil {
+			nodes = append(nodes, node)
+		}
+		if p.closing != nil {
+			return spaceTexts(nodes)
+		}
+	}
+}
+
+// parseCloseTag parses the closing tag starting at curToken,
+// returning nil if the tag is malformed.
+func (p *Parser) parseCloseTag() *closeTag {
+	assert.Assert(p.isCurToken(token.LEFT_CHEVRON), "expected left chevron")
+
+	tag := &closeTag{LeftChevron: p.curToken.Pos}
+	p.nextToken() // skip '/'
 
 	if !p.expectPeek(token.IDENT) {
+		p.syncTag()
 		return nil
 	}
 
-	if p.curToken.Literal != element.Name.Name {
-		p.errorf("unexpected closing tag %s, expected %s", p.curToken.Literal, element.Name.Name)
-		return nil
+	tag.Name = &ast.Ident{
+		Position: p.curToken.Pos,
+		Name:     p.curToken.Literal,
 	}
 
 	if !p.expectPeek(token.RIGHT_CHEVRON) {
-		return nil
+		p.syncTag()
 	}
 
-	element.RightChevron = p.curToken.Pos
+	return tag
+}
 
-	return element
+// syncTag skips the tokens of a malformed tag up to and
+// including its '>', reporting whether the tag was ended and
+// whether it was self closing. Tags cut short by the start of
+// another tag, or the end of the file, are left unended.
+func (p *Parser) syncTag() (ended bool, selfClosing bool) {
+	for !p.isPeekToken(token.RIGHT_CHEVRON) &&
+		!p.isPeekToken(token.LEFT_CHEVRON) &&
+		!p.isPeekToken(token.EOF) {
+		p.nextToken()
+	}
+
+	selfClosing = p.isCurToken(token.SLASH)
+	if !p.tryPeek(token.RIGHT_CHEVRON) {
+		return false, false
+	}
+	return true, selfClosing
+}
+
+// badNode returns an *ast.BadNode spanning from
+// up to and including curToken.
+func (p *Parser) badNode(from source.Pos) *ast.BadNode {
+	to := p.curToken.Pos + source.Pos(len(p.curToken.Literal))
+	if to < from {
+		to = from
+	}
+	return &ast.BadNode{From: from, To: to}
 }
 
 func (p *Parser) parseAttribute() *ast.Attribute {
@@ -253,6 +544,11 @@ func (p *Parser) parseAttribute() *ast.Attribute {
 		return attr
 	}
 
+	if p.tryPeek(token.GO_EXPRESSION) {
+		attr.ValueExpr = p.parseExpr()
+		return attr
+	}
+
 	if !p.expectPeek(token.QUOTE) {
 		return nil
 	}
@@ -268,6 +564,71 @@ func (p *Parser) parseAttribute() *ast.Attribute {
 	return attr
 }
 
+func (p *Parser) parseDirective() *ast.Directive {
+	assert.Assert(p.isCurToken(token.IDENT) || p.isCurToken(token.ON), "expected curToken to be a directive kind")
+
+	dir := &ast.Directive{
+		Kind: &ast.Ident{
+			Position: p.curToken.Pos,
+			Name:     p.curToken.Literal,
+		},
+	}
+
+	valid := true
+	switch dir.Kind.Name {
+	case ast.DirectiveOn, ast.DirectiveBind:
+	default:
+		p.errorf(dir.Kind.Pos(), "unknown directive %s, expected %s or %s", dir.Kind.Name, ast.DirectiveOn, ast.DirectiveBind)
+		valid = false
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	dir.Name = &ast.Ident{
+		Position: p.curToken.Pos,
+		Name:     p.curToken.Literal,
+	}
+
+	if !p.tryPeek(token.ASSIGN) {
+		p.errorf(dir.Name.End(), "directive %s:%s requires a value", dir.Kind.Name, dir.Name.Name)
+		return nil
+	}
+
+	if p.tryPeek(token.QUOTE) {
+		p.tryPeek(token.TEXT)
+		p.tryPeek(token.QUOTE)
+		p.errorf(dir.Name.End()+1, "directive %s:%s requires a Go expression value, got a string", dir.Kind.Name, dir.Name.Name)
+		return nil
+	}
+
+	if !p.expectPeek(token.GO_EXPRESSION) {
+		return nil
+	}
+
+	dir.Handler = p.parseExpr()
+
+	if !valid {
+		return nil
+	}
+	return dir
+}
+
+func (p *Parser) parseExpr() *ast.Expr {
+	assert.Assert(p.isCurToken(token.GO_EXPRESSION), "expected curToken to be GO_EXPRESSION")
+
+	return &ast.Expr{
+		Lbrace: p.curToken.Pos - 1,
+		Code:   p.curToken.Literal,
+		Rbrace: p.curToken.Pos + source.Pos(len(p.curToken.Literal)),
+	}
+}
+
 func (p *Parser) isCurToken(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -298,12 +659,20 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	return true
 }
 
+// peekError reports an unexpected peekToken, which
+// leaves the parser out of sync until the next sync point.
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorf(p.peekToken.Pos, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
+	p.syncing = true
+}
+
+func (p *Parser) error(pos source.Pos, msg string) {
+	if p.syncing {
+		return
+	}
+	p.errors.Add(p.file.Position(pos), msg)
 }
 
-func (p *Parser) errorf(format string, v ...any) {
-	msg := fmt.Sprintf(format, v...)
-	p.errors = append(p.errors, msg)
+func (p *Parser) errorf(pos source.Pos, format string, v ...any) {
+	p.error(pos, fmt.Sprintf(format, v...))
 }
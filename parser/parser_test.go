@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"fmt"
 	source "go/token"
 	"slices"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -94,6 +96,7 @@ func _() {}
 			assert.Equal(t, "func _() {}\n", n.Code)
 			assert.Equal(t, source.Pos(1), n.Pos())
 			assert.Equal(t, source.Pos(17), n.End())
+			assert.Equal(t, source.Pos(5), n.CodePos)
 			didParseCodeBlock = true
 		}
 		return true
@@ -132,6 +135,22 @@ func TestAttribute(t *testing.T) {
 		})
 	})
 
+	t.Run(`expression value`, func(t *testing.T) {
+		input := `<test class={c.classes()}/>`
+		el, err := ParseElement(input)
+		assert.NoError(t, err)
+		require.NotNil(t, el)
+		require.Len(t, el.Attrs, 1)
+
+		attr := el.Attrs[0]
+		assert.Equal(t, "class", attr.Name.Name)
+		assert.Equal(t, "", attr.ValueLiteral)
+		require.NotNil(t, attr.ValueExpr)
+		assert.Equal(t, "c.classes()", attr.ValueExpr.Code)
+		assert.Equal(t, source.Pos(13), attr.ValueExpr.Pos())
+		assert.Equal(t, attr.ValueExpr.End(), attr.End())
+	})
+
 	t.Run(`multiline attributes`, func(t *testing.T) {
 		input := `<test
 			meep="meep"
@@ -150,6 +169,40 @@ func TestAttribute(t *testing.T) {
 	})
 }
 
+func TestDirective(t *testing.T) {
+	t.Run(`on and bind`, func(t *testing.T) {
+		input := `<input on:click={c.handleClick} type="text" bind:value={c.setText}/>`
+		el, err := ParseElement(input)
+		assert.NoError(t, err)
+		require.NotNil(t, el)
+		require.Len(t, el.Attrs, 1)
+		require.Len(t, el.Directives, 2)
+
+		on := el.Directives[0]
+		assert.Equal(t, ast.DirectiveOn, on.Kind.Name)
+		assert.Equal(t, "click", on.Name.Name)
+		assert.Equal(t, "c.handleClick", on.Handler.Code)
+		assert.Equal(t, source.Pos(8), on.Pos())
+
+		bind := el.Directives[1]
+		assert.Equal(t, ast.DirectiveBind, bind.Kind.Name)
+		assert.Equal(t, "value", bind.Name.Name)
+		assert.Equal(t, "c.setText", bind.Handler.Code)
+	})
+
+	t.Run(`unknown kind`, func(t *testing.T) {
+		input := `<input meep:click={c.handleClick}/>`
+		_, err := ParseElement(input)
+		assert.ErrorContains(t, err, "unknown directive meep")
+	})
+
+	t.Run(`string value`, func(t *testing.T) {
+		input := `<input on:click="handleClick"/>`
+		_, err := ParseElement(input)
+		assert.ErrorContains(t, err, "requires a Go expression value")
+	})
+}
+
 func noParserErrors(t *testing.T, p *Parser) {
 	errs := p.Errors()
 	if assert.Empty(t, errs, "expected no errors") {
@@ -161,3 +214,223 @@ func noParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestExpression(t *testing.T) {
+	input := `<span>Hello {c.Name}</span>`
+	el, err := ParseElement(input)
+	assert.NoError(t, err)
+	require.NotNil(t, el)
+	require.Len(t, el.Nodes, 2)
+
+	text, ok := el.Nodes[0].(*ast.Text)
+	require.True(t, ok, "expected first child to be text")
+	assert.Equal(t, "Hello ", text.Literal, "expected the space before the expression to be kept")
+
+	expr, ok := el.Nodes[1].(*ast.Expr)
+	require.True(t, ok, "expected second child to be an expression")
+	assert.Equal(t, "c.Name", expr.Code)
+	assert.Equal(t, source.Pos(13), expr.Pos())
+	assert.Equal(t, source.Pos(21), expr.End())
+}
+
+func TestTextWhiteSpace(t *testing.T) {
+	input := "<p>\n\tHello  <b>world</b>\n\t<i>again</i>\n\t, {c.Name}!</p>"
+	el, err := ParseElement(input)
+	require.NoError(t, err)
+
+	literals := make([]string, 0)
+	for _, n := range el.Nodes {
+		if text, ok := n.(*ast.Text); ok {
+			literals = append(literals, text.Literal)
+		}
+	}
+	assert.Equal(t, []string{" Hello ", " ", " , ", "!"}, literals, "expected runs of white space to be collapsed")
+	assert.Len(t, el.Nodes, 7)
+
+	el, err = ParseElement("<ul>\n\t<li>a</li>\n\t<li>b</li>\n</ul>")
+	require.NoError(t, err)
+	assert.Len(t, el.Nodes, 2, "expected white space breaking lines next to block-level elements to be dropped")
+}
+
+func TestIfBlock(t *testing.T) {
+	input := `<div>{#if c.loading}<p>Loading</p>{:else if c.err != nil}{c.err}{:else}done{/if}</div>`
+	el, err := ParseElement(input)
+	require.NoError(t, err)
+	require.Len(t, el.Nodes, 1)
+
+	block, ok := el.Nodes[0].(*ast.IfBlock)
+	require.True(t, ok, "expected *ast.IfBlock, got %T", el.Nodes[0])
+	assert.Equal(t, "c.loading", block.Cond.Code)
+	require.Len(t, block.Then.Nodes, 1)
+	assert.IsType(t, &ast.Element{}, block.Then.Nodes[0])
+	assert.Equal(t, source.Pos(6), block.Pos())
+	assert.Equal(t, source.Pos(81), block.End())
+
+	elseIf, ok := block.Else.(*ast.IfBlock)
+	require.True(t, ok, "expected else if branch, got %T", block.Else)
+	assert.Equal(t, "c.err != nil", elseIf.Cond.Code)
+	require.Len(t, elseIf.Then.Nodes, 1)
+	assert.IsType(t, &ast.Expr{}, elseIf.Then.Nodes[0])
+	assert.Equal(t, block.End(), elseIf.End())
+
+	els, ok := elseIf.Else.(*ast.Fragment)
+	require.True(t, ok, "expected else branch, got %T", elseIf.Else)
+	require.Len(t, els.Nodes, 1)
+	assert.Equal(t, "done", els.Nodes[0].(*ast.Text).Literal)
+
+	var blocks int
+	ast.Inspect(el, func(n ast.Node) bool {
+		if _, ok := n.(*ast.IfBlock); ok {
+			blocks++
+		}
+		return true
+	})
+	assert.Equal(t, 2, blocks)
+}
+
+func TestEachBlock(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		tests := []struct {
+			input string
+			expr  string
+			value string
+			index string
+			key   string
+		}{
+			{`{#each c.items}`, "c.items", "", "", ""},
+			{`{#each c.items as item}`, "c.items ", "item", "", ""},
+			{`{#each c.items as item, i}`, "c.items ", "item", "i", ""},
+			{`{#each c.items as item (item.ID)}`, "c.items ", "item", "", "item.ID"},
+			{`{#each c.byName["a as b"] as item, i (key(item, i))}`, `c.byName["a as b"] `, "item", "i", "key(item, i)"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				el, err := ParseElement("<ul>" + tt.input + "<li></li>{/each}</ul>")
+				require.NoError(t, err)
+				require.Len(t, el.Nodes, 1)
+
+				block, ok := el.Nodes[0].(*ast.EachBlock)
+				require.True(t, ok, "expected *ast.EachBlock, got %T", el.Nodes[0])
+				assert.Equal(t, tt.expr, block.Expr.Code)
+				if tt.value != "" {
+					require.NotNil(t, block.Value)
+					assert.Equal(t, tt.value, block.Value.Name)
+				} else {
+					assert.Nil(t, block.Value)
+				}
+				if tt.index != "" {
+					require.NotNil(t, block.Index)
+					assert.Equal(t, tt.index, block.Index.Name)
+				} else {
+					assert.Nil(t, block.Index)
+				}
+				if tt.key != "" {
+					require.NotNil(t, block.Key)
+					assert.Equal(t, tt.key, block.Key.Code)
+				} else {
+					assert.Nil(t, block.Key)
+				}
+				require.Len(t, block.Body.Nodes, 1)
+				assert.Nil(t, block.Else)
+			})
+		}
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		input := `{#each c.items as item, i (item.ID)}<li/>{:else}none{/each}`
+		file, err := ParseFile(source.NewFileSet(), "", input)
+		require.NoError(t, err)
+
+		block := file.Fragment.Nodes[0].(*ast.EachBlock)
+		assert.Equal(t, source.Pos(1), block.Pos())
+		assert.Equal(t, source.Pos(len(input)+1), block.End())
+		assert.Equal(t, source.Pos(19), block.Value.Pos())
+		assert.Equal(t, source.Pos(25), block.Index.Pos())
+		assert.Equal(t, source.Pos(27), block.Key.Pos())
+		assert.Equal(t, source.Pos(36), block.Key.End())
+
+		require.NotNil(t, block.Else)
+		require.Len(t, block.Else.Nodes, 1)
+		assert.Equal(t, "none", block.Else.Nodes[0].(*ast.Text).Literal)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		tests := map[string]string{
+			`{#each}{/each}`:                     "1:7: missing expression in {#each}",
+			`{#each c.items as}{/each}`:          "1:18: expected variable name after as in {#each}, got EOF",
+			`{#each c.items as a, 1}{/each}`:     "1:22: expected index variable name after ',' in {#each}, got INT",
+			`{#each c.items as a (a.ID}{/each}`:  "1:26: expected ')' to close key in {#each}",
+			`{#each c.items as a b}{/each}`:      "1:21: unexpected IDENT in {#each}",
+			`{#each c.items}{:else if x}{/each}`: "1:16: unexpected {:else if} in each block",
+			`{#each c.items}{/if}{/each}`:        "1:16: unexpected {/if}, expected {/each}",
+			`{#each c.items}<p>`:                 "1:1: unclosed each block",
+		}
+		for input, expected := range tests {
+			t.Run(input, func(t *testing.T) {
+				_, err := ParseFile(source.NewFileSet(), "", input)
+				var list ErrorList
+				require.ErrorAs(t, err, &list)
+				assert.Equal(t, expected, list[0].Error())
+			})
+		}
+	})
+}
+
+func TestSlot(t *testing.T) {
+	t.Run("named with fallback", func(t *testing.T) {
+		input := `<div><slot/><slot name="footer"><p>none</p></slot></div>`
+		el, err := ParseElement(input)
+		require.NoError(t, err)
+		require.Len(t, el.Nodes, 2)
+
+		def, ok := el.Nodes[0].(*ast.Slot)
+		require.True(t, ok, "expected *ast.Slot, got %T", el.Nodes[0])
+		assert.Equal(t, "", def.Name)
+		assert.Empty(t, def.Fallback.Nodes)
+
+		footer, ok := el.Nodes[1].(*ast.Slot)
+		require.True(t, ok, "expected *ast.Slot, got %T", el.Nodes[1])
+		assert.Equal(t, "footer", footer.Name)
+		assert.Equal(t, source.Pos(13), footer.Pos())
+		assert.Equal(t, source.Pos(50), footer.End())
+		require.Len(t, footer.Fallback.Nodes, 1)
+		assert.Equal(t, "p", footer.Fallback.Nodes[0].(*ast.Element).Name.Name)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		tests := map[string]string{
+			`<slot class="a"/>`:          "1:7: unexpected attribute class on slot, expected name",
+			`<slot name={c.name}/>`:      "1:12: slot name must be a string literal",
+			`<slot name=""/>`:            "1:7: slot name must not be empty",
+			`<slot name="a" name="b"/>`:  "1:16: slot name set more than once",
+			`<slot on:click={c.click}/>`: "1:7: unexpected directive on:click on slot",
+		}
+		for input, expected := range tests {
+			t.Run(input, func(t *testing.T) {
+				_, err := ParseFile(source.NewFileSet(), "", input)
+				var list ErrorList
+				require.ErrorAs(t, err, &list)
+				assert.Equal(t, expected, list[0].Error())
+			})
+		}
+	})
+}
+
+func TestParseFileConcurrent(t *testing.T) {
+	fset := source.NewFileSet()
+	files := make([]*ast.File, 8)
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			files[i], _ = ParseFile(fset, fmt.Sprintf("File%d.flamingo", i), "<p>{c.mino}</p>")
+		}()
+	}
+	wg.Wait()
+
+	for i, file := range files {
+		require.NotNil(t, file)
+		assert.Equal(t, fmt.Sprintf("File%d.flamingo:1:1", i), fset.Position(file.Pos()).String(), "expected each file to have its own positions")
+	}
+}
@@ -0,0 +1,71 @@
+package reactive
+
+import "slices"
+
+// An Effect runs a function whenever one of the
+// Signals or Computed values it read changes.
+//
+// Effects created as an Effect runs are owned by it: they
+// are stopped as it runs again and as it is stopped.
+type Effect struct {
+	node
+	owned []*Effect
+}
+
+// NewEffect runs fn and returns an Effect of rt running it
+// again as the values it read change, until it is stopped.
+func NewEffect(rt *Runtime, fn func()) *Effect {
+	e := &Effect{}
+	e.rt, e.effect = rt, true
+	if rt.owner != nil {
+		rt.owner.owned = append(rt.owner.owned, e)
+	}
+	e.run = func() bool {
+		e.stopOwned()
+		owner := rt.owner
+		rt.owner = e
+		defer func() {
+			rt.owner = owner
+		}()
+		fn()
+		return false
+	}
+	e.Run()
+	return e
+}
+
+// Run runs the function of e now, tracking the values
+// it reads anew. Stopped Effects are not run.
+func (e *Effect) Run() {
+	if e.stopped {
+		return
+	}
+	if e.running {
+		panic(ErrCycle)
+	}
+	// Effects of the signals set by e run once it returns
+	e.rt.Batch(e.update)
+}
+
+// Stop stops e, and the Effects it owns, from running again.
+func (e *Effect) Stop() {
+	e.stopped = true
+	e.stopOwned()
+	for _, s := range e.sources {
+		s.unobserve(&e.node)
+	}
+	e.sources = nil
+	e.rt.pending = slices.DeleteFunc(e.rt.pending, func(n *node) bool { return n == &e.node })
+}
+
+// Stopped reports whether e is stopped.
+func (e *Effect) Stopped() bool {
+	return e.stopped
+}
+
+func (e *Effect) stopOwned() {
+	for _, o := range e.owned {
+		o.Stop()
+	}
+	e.owned = nil
+}
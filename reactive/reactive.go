@@ -0,0 +1,242 @@
+// Package reactive provides signals for fine-grained reactivity.
+//
+// A Signal holds a value which, as it is set, notifies the
+// Computed values and Effects which read it. Dependencies are
+// tracked as Computed values and Effects run, so that they are
+// run again only when a value they read last time changes.
+//
+// Changes propagate in two phases. Setting a signal marks the
+// nodes depending on it as possibly stale without running them.
+// Effects are then run in the order they became stale, and run
+// only if one of their dependencies did in fact change, Computed
+// values being recomputed lazily as they are checked. A Computed
+// value which is recomputed to an equal value does not cause the
+// Effects depending on it to run.
+//
+// Signals, Computed values and Effects belong to the Runtime they
+// are created with, which tracks the values they read. They are
+// not safe for concurrent use: a Runtime and its graph must only be
+// used from a single goroutine, while graphs of different Runtimes
+// may be used concurrently.
+package reactive
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+)
+
+// ErrCycle is the value panicked with when a Computed value
+// depends on itself or Effects keep setting signals they
+// depend on.
+var ErrCycle = errors.New("reactive: cycle detected")
+
+// maxFlushes is the number of times Effects may be run again by
+// the Effects of a single flush before a cycle is reported.
+const maxFlushes = 100
+
+type state int
+
+const (
+	clean state = iota
+	check       // a dependency of a dependency may have changed
+	dirty       // a dependency changed
+)
+
+// node is a signal, computed value or effect
+// in the dependency graph.
+type node struct {
+	state     state
+	sources   []*node // nodes read by the last run
+	observers []*node // nodes which read this node in their last run
+
+	// run runs a computed value or effect, reporting whether its
+	// value changed. It is nil for signals, which are always clean.
+	run     func() bool
+	effect  bool
+	running bool
+	stopped bool
+	rt      *Runtime
+}
+
+// A Runtime holds the state of a graph of Signals, Computed values
+// and Effects as they are run. Values are only tracked as they are
+// read by the Computed values and Effects of their own Runtime.
+// The zero value is ready to use.
+type Runtime struct {
+	// observer is the node being run, which
+	// the nodes it reads are tracked for
+	observer *node
+	// owner is the Effect being run, which
+	// the Effects created are owned by
+	owner      *Effect
+	batchDepth int
+	flushing   bool
+	// pending are the effects which became stale,
+	// in the order they did
+	pending []*node
+}
+
+// NewRuntime returns a Runtime for a new graph.
+func NewRuntime() *Runtime {
+	return &Runtime{}
+}
+
+// track records n as a dependency of the node being run.
+func (n *node) track() {
+	observer := n.rt.observer
+	if observer == nil {
+		return
+	}
+	if !slices.Contains(observer.sources, n) {
+		observer.sources = append(observer.sources, n)
+	}
+	if !slices.Contains(n.observers, observer) {
+		n.observers = append(n.observers, observer)
+	}
+}
+
+// stale marks n as being in state s, unless it is in a worse
+// state already, and its observers as possibly stale.
+func (n *node) stale(s state) {
+	if n.state >= s {
+		return
+	}
+	if n.state == clean && n.effect && !n.stopped {
+		n.rt.pending = append(n.rt.pending, n)
+	}
+	n.state = s
+	for _, o := range n.observers {
+		o.stale(check)
+	}
+}
+
+// changed marks the observers of n as stale
+// following a change of the value of n.
+func (n *node) changed() {
+	n.rt.Batch(func() {
+		for _, o := range n.observers {
+			o.stale(dirty)
+		}
+	})
+}
+
+// refresh runs n if it is stale and one of its
+// dependencies changed, leaving it clean.
+func (n *node) refresh() {
+	if n.state == check {
+		for _, s := range n.sources {
+			if s.run != nil {
+				s.refresh()
+			}
+			if n.state == dirty {
+				break
+			}
+		}
+	}
+	if n.state == dirty {
+		n.update()
+		return
+	}
+	n.state = clean
+}
+
+// update runs n, tracking the nodes it reads anew. As n is marked
+// clean before it runs, signals it sets while it runs mark it stale.
+func (n *node) update() {
+	n.state = clean
+	old := n.sources
+	n.sources = nil
+
+	prev := n.rt.observer
+	n.rt.observer, n.running = n, true
+	defer func() {
+		n.rt.observer, n.running = prev, false
+	}()
+	changed := n.run()
+
+	for _, s := range old {
+		if !slices.Contains(n.sources, s) {
+			s.unobserve(n)
+		}
+	}
+
+	// The observers are in the stale state already, as marked by
+	// our sources, except for those reading n as they are running
+	if changed {
+		for _, o := range n.observers {
+			if !o.running {
+				o.state = dirty
+			}
+		}
+	}
+}
+
+// unobserve removes o from the observers of n.
+func (n *node) unobserve(o *node) {
+	n.observers = slices.DeleteFunc(n.observers, func(other *node) bool { return other == o })
+}
+
+// Batch runs fn, deferring Effects of the signals set by
+// fn until it returns. Batches may be nested, in which
+// case Effects run once the outermost batch returns.
+func (rt *Runtime) Batch(fn func()) {
+	rt.batchDepth++
+	defer func() {
+		rt.batchDepth--
+		if rt.batchDepth == 0 && !rt.flushing {
+			rt.flush()
+		}
+	}()
+	fn()
+}
+
+// flush runs the pending effects, including those which
+// become pending as the pending effects are run.
+func (rt *Runtime) flush() {
+	rt.flushing = true
+	defer func() {
+		rt.flushing = false
+	}()
+
+	for flushes := 0; len(rt.pending) > 0; flushes++ {
+		if flushes == maxFlushes {
+			rt.pending = nil
+			panic(ErrCycle)
+		}
+
+		effects := rt.pending
+		rt.pending = nil
+		for _, e := range effects {
+			if !e.stopped {
+				e.refresh()
+			}
+		}
+	}
+}
+
+// Untrack runs fn without tracking the values it
+// reads as dependencies of the Computed value or
+// Effect being run.
+func (rt *Runtime) Untrack(fn func()) {
+	prev := rt.observer
+	rt.observer = nil
+	defer func() {
+		rt.observer = prev
+	}()
+	fn()
+}
+
+// equal reports whether a and b are equal, treating values
+// which are not comparable as never being equal.
+func equal(a, b any) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		// Either is a nil interface
+		return va.IsValid() == vb.IsValid()
+	}
+	if !va.Comparable() || !vb.Comparable() {
+		return false
+	}
+	return a == b
+}
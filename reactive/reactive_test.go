@@ -0,0 +1,295 @@
+package reactive
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignal(t *testing.T) {
+	rt := NewRuntime()
+	s := NewSignal(rt, 1)
+	assert.Equal(t, 1, s.Get())
+
+	s.Set(2)
+	assert.Equal(t, 2, s.Peek())
+
+	s.Update(func(v int) int { return v * 10 })
+	assert.Equal(t, 20, s.Get())
+}
+
+func TestEffect(t *testing.T) {
+	t.Run("runs on change", func(t *testing.T) {
+		rt := NewRuntime()
+		count := NewSignal(rt, 0)
+		var seen []int
+		NewEffect(rt, func() { seen = append(seen, count.Get()) })
+		assert.Equal(t, []int{0}, seen, "expected effect to run when created")
+
+		count.Set(1)
+		count.Set(1)
+		count.Set(2)
+		assert.Equal(t, []int{0, 1, 2}, seen, "expected effect to run once per change")
+	})
+
+	t.Run("untracked reads", func(t *testing.T) {
+		rt := NewRuntime()
+		a, b := NewSignal(rt, "a"), NewSignal(rt, "b")
+		runs := 0
+		NewEffect(rt, func() {
+			runs++
+			a.Get()
+			b.Peek()
+			rt.Untrack(func() { a.Get() })
+		})
+
+		b.Set("c")
+		assert.Equal(t, 1, runs, "expected peeked signal not to be tracked")
+		a.Set("d")
+		assert.Equal(t, 2, runs)
+	})
+
+	t.Run("dynamic dependencies", func(t *testing.T) {
+		rt := NewRuntime()
+		useA := NewSignal(rt, true)
+		a, b := NewSignal(rt, 1), NewSignal(rt, 2)
+		var seen []int
+		NewEffect(rt, func() {
+			if useA.Get() {
+				seen = append(seen, a.Get())
+			} else {
+				seen = append(seen, b.Get())
+			}
+		})
+
+		b.Set(3)
+		assert.Equal(t, []int{1}, seen, "expected unread signal not to be a dependency")
+
+		useA.Set(false)
+		a.Set(4)
+		assert.Equal(t, []int{1, 3}, seen, "expected signal no longer read to be dropped")
+		b.Set(5)
+		assert.Equal(t, []int{1, 3, 5}, seen)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		rt := NewRuntime()
+		s := NewSignal(rt, 0)
+		runs := 0
+		e := NewEffect(rt, func() {
+			runs++
+			s.Get()
+		})
+
+		e.Stop()
+		s.Set(1)
+		e.Run()
+		assert.Equal(t, 1, runs, "expected stopped effect not to run")
+		assert.Empty(t, s.observers)
+	})
+
+	t.Run("owned", func(t *testing.T) {
+		rt := NewRuntime()
+		outer, inner := NewSignal(rt, 0), NewSignal(rt, 0)
+		runs := 0
+		e := NewEffect(rt, func() {
+			outer.Get()
+			NewEffect(rt, func() {
+				runs++
+				inner.Get()
+			})
+		})
+
+		outer.Set(1)
+		runs = 0
+		inner.Set(1)
+		assert.Equal(t, 1, runs, "expected the effects created by the previous run to be stopped")
+
+		e.Stop()
+		runs = 0
+		inner.Set(2)
+		assert.Zero(t, runs, "expected the effects owned to be stopped along")
+		assert.Empty(t, inner.observers)
+	})
+
+	t.Run("stop pending", func(t *testing.T) {
+		rt := NewRuntime()
+		s := NewSignal(rt, 0)
+		runs := 0
+		var e *Effect
+		rt.Batch(func() {
+			e = NewEffect(rt, func() {
+				runs++
+				s.Get()
+			})
+			s.Set(1)
+			e.Stop()
+		})
+		assert.Equal(t, 1, runs, "expected effect stopped while pending not to run")
+	})
+
+	t.Run("sets its dependency", func(t *testing.T) {
+		rt := NewRuntime()
+		s := NewSignal(rt, 0)
+		runs := 0
+		NewEffect(rt, func() {
+			runs++
+			if v := s.Get(); v < 3 {
+				s.Set(v + 1)
+			}
+		})
+		assert.Equal(t, 3, s.Peek())
+		assert.Equal(t, 4, runs)
+	})
+
+	t.Run("not comparable", func(t *testing.T) {
+		rt := NewRuntime()
+		s := NewSignal(rt, []int{1})
+		runs := 0
+		NewEffect(rt, func() {
+			runs++
+			s.Get()
+		})
+		s.Set(s.Peek())
+		assert.Equal(t, 2, runs, "expected values which are not comparable never to be equal")
+
+		var nilValue any
+		a := NewSignal(rt, nilValue)
+		NewEffect(rt, func() {
+			runs++
+			a.Get()
+		})
+		a.Set(nil)
+		assert.Equal(t, 3, runs, "expected nil to equal nil")
+		a.Set([]int{})
+		a.Set(func() {})
+		assert.Equal(t, 5, runs)
+	})
+}
+
+func TestComputed(t *testing.T) {
+	t.Run("lazy", func(t *testing.T) {
+		rt := NewRuntime()
+		s := NewSignal(rt, 2)
+		runs := 0
+		double := NewComputed(rt, func() int {
+			runs++
+			return s.Get() * 2
+		})
+		assert.Equal(t, 0, runs, "expected computed not to be computed before read")
+
+		assert.Equal(t, 4, double.Get())
+		assert.Equal(t, 4, double.Peek())
+		assert.Equal(t, 1, runs, "expected computed value to be cached")
+
+		s.Set(3)
+		assert.Equal(t, 1, runs, "expected unread computed not to be computed")
+		assert.Equal(t, 6, double.Get())
+		assert.Equal(t, 2, runs)
+	})
+
+	t.Run("equal value", func(t *testing.T) {
+		rt := NewRuntime()
+		s := NewSignal(rt, 1)
+		parity := NewComputed(rt, func() bool { return s.Get()%2 == 0 })
+		var seen []bool
+		NewEffect(rt, func() { seen = append(seen, parity.Get()) })
+
+		s.Set(3)
+		assert.Equal(t, []bool{false}, seen, "expected effect not to run for an equal computed value")
+		s.Set(4)
+		assert.Equal(t, []bool{false, true}, seen)
+	})
+
+	t.Run("diamond", func(t *testing.T) {
+		rt := NewRuntime()
+		s := NewSignal(rt, 1)
+		a := NewComputed(rt, func() int { return s.Get() + 1 })
+		b := NewComputed(rt, func() int { return s.Get() * 2 })
+		runs := 0
+		sum := NewComputed(rt, func() int {
+			runs++
+			return a.Get() + b.Get()
+		})
+		var seen []int
+		NewEffect(rt, func() { seen = append(seen, sum.Get()) })
+
+		s.Set(2)
+		assert.Equal(t, []int{4, 7}, seen, "expected effect to observe a consistent state")
+		assert.Equal(t, 2, runs, "expected computed to run once per change")
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		rt := NewRuntime()
+		var b *Computed[int]
+		a := NewComputed(rt, func() int { return b.Get() + 1 })
+		b = NewComputed(rt, func() int { return a.Get() + 1 })
+		assert.PanicsWithValue(t, ErrCycle, func() { a.Get() })
+
+		// The graph is left usable
+		s := NewSignal(rt, 1)
+		c := NewComputed(rt, func() int { return s.Get() })
+		assert.Equal(t, 1, c.Get())
+		assert.Nil(t, rt.observer)
+	})
+}
+
+func TestBatch(t *testing.T) {
+	rt := NewRuntime()
+	a, b := NewSignal(rt, 1), NewSignal(rt, 2)
+	var seen []int
+	NewEffect(rt, func() { seen = append(seen, a.Get()+b.Get()) })
+
+	rt.Batch(func() {
+		a.Set(10)
+		rt.Batch(func() {
+			b.Set(20)
+		})
+		assert.Equal(t, []int{3}, seen, "expected effects to be deferred to the outermost batch")
+	})
+	assert.Equal(t, []int{3, 30}, seen)
+}
+
+func TestEffectCycle(t *testing.T) {
+	rt := NewRuntime()
+	s := NewSignal(rt, 0)
+	require.PanicsWithValue(t, ErrCycle, func() {
+		NewEffect(rt, func() { s.Set(s.Get() + 1) })
+	})
+	assert.Empty(t, rt.pending, "expected pending effects to be dropped")
+	assert.False(t, rt.flushing)
+	assert.Zero(t, rt.batchDepth)
+
+	// Effects still run after a cycle
+	other := NewSignal(rt, 0)
+	runs := 0
+	NewEffect(rt, func() {
+		runs++
+		other.Get()
+	})
+	other.Set(1)
+	assert.Equal(t, 2, runs)
+}
+
+func TestRuntimes(t *testing.T) {
+	// Run with -race to check that graphs of
+	// different runtimes share no state
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rt := NewRuntime()
+			s := NewSignal(rt, 0)
+			sum := 0
+			NewEffect(rt, func() { sum += s.Get() })
+			for i := 1; i <= 100; i++ {
+				s.Set(i)
+			}
+			assert.Equal(t, 5050, sum)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,90 @@
+package reactive
+
+// A Signal holds a value which Computed values and Effects
+// reading it depend on. A Signal must not be copied after
+// first use.
+type Signal[T any] struct {
+	node
+	value T
+}
+
+// NewSignal returns a Signal of rt holding value.
+func NewSignal[T any](rt *Runtime, value T) *Signal[T] {
+	s := &Signal[T]{value: value}
+	s.rt = rt
+	return s
+}
+
+// Get returns the value of s, tracking s as a dependency
+// of the Computed value or Effect being run.
+func (s *Signal[T]) Get() T {
+	s.track()
+	return s.value
+}
+
+// Peek returns the value of s without tracking it.
+func (s *Signal[T]) Peek() T {
+	return s.value
+}
+
+// Set sets the value of s to value, running the Effects
+// depending on it unless value equals the current value.
+// Values which are not comparable are never equal.
+func (s *Signal[T]) Set(value T) {
+	if equal(s.value, value) {
+		return
+	}
+	s.value = value
+	s.changed()
+}
+
+// Update sets the value of s to the result of
+// fn applied to the current value of s.
+func (s *Signal[T]) Update(fn func(T) T) {
+	s.Set(fn(s.value))
+}
+
+// A Computed value is derived from Signals and other Computed
+// values. It is computed lazily as it is read, and computed
+// again only once one of the values it read changes.
+type Computed[T any] struct {
+	node
+	value T
+	fn    func() T
+}
+
+// NewComputed returns a Computed value of rt computed by fn.
+func NewComputed[T any](rt *Runtime, fn func() T) *Computed[T] {
+	c := &Computed[T]{fn: fn}
+	c.rt, c.state = rt, dirty
+	c.run = func() bool {
+		value := c.fn()
+		changed := !equal(c.value, value)
+		c.value = value
+		return changed
+	}
+	return c
+}
+
+// Get returns the value of c, computing it if needed, and
+// tracks c as a dependency of the Computed value or Effect
+// being run. It panics with ErrCycle if computing c requires
+// the value of c.
+func (c *Computed[T]) Get() T {
+	if c.running {
+		panic(ErrCycle)
+	}
+	c.track()
+	c.refresh()
+	return c.value
+}
+
+// Peek returns the value of c, computing
+// it if needed, without tracking it.
+func (c *Computed[T]) Peek() T {
+	if c.running {
+		panic(ErrCycle)
+	}
+	c.refresh()
+	return c.value
+}
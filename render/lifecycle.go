@@ -0,0 +1,92 @@
+package render
+
+import "slices"
+
+// Mounter is implemented by component instances that need
+// to run code once their output is part of the document.
+type Mounter interface {
+	OnMount()
+}
+
+// Unmounter is implemented by component instances that need
+// to release resources before their output is removed.
+type Unmounter interface {
+	OnUnmount()
+}
+
+// Updater is implemented by component instances that need
+// to run code around updates to their output.
+type Updater interface {
+	BeforeUpdate()
+	AfterUpdate()
+}
+
+// Lifecycle tracks the component instances attached to
+// Components and invokes their lifecycle hooks. Renderers
+// embed it to implement Attach and Update.
+//
+// Hooks are ordered so that a parent observes its children
+// in a consistent state: OnMount and AfterUpdate are invoked
+// on children before their parents, OnUnmount and BeforeUpdate
+// on parents before their children.
+type Lifecycle struct {
+	// Instances attached to the same Component are kept in the
+	// order they were attached, that is innermost first, as a
+	// child component is constructed before its parent finishes.
+	instances map[Component][]any
+}
+
+// Attach registers instance as a component instance whose
+// output is rooted at root. Components rendering more than
+// one root attach to the first of them.
+func (l *Lifecycle) Attach(root Component, instance any) {
+	if l.instances == nil {
+		l.instances = make(map[Component][]any)
+	}
+	l.instances[root] = append(l.instances[root], instance)
+}
+
+// Mount invokes OnMount on the instances attached
+// to c and its descendants, children first.
+func (l *Lifecycle) Mount(c Component) {
+	for _, child := range c.Children() {
+		l.Mount(child)
+	}
+	for _, inst := range l.instances[c] {
+		if m, ok := inst.(Mounter); ok {
+			m.OnMount()
+		}
+	}
+}
+
+// Unmount invokes OnUnmount on the instances attached to c
+// and its descendants, parents first, and detaches them.
+func (l *Lifecycle) Unmount(c Component) {
+	insts := l.instances[c]
+	delete(l.instances, c)
+	for _, inst := range slices.Backward(insts) {
+		if u, ok := inst.(Unmounter); ok {
+			u.OnUnmount()
+		}
+	}
+	for _, child := range c.Children() {
+		l.Unmount(child)
+	}
+}
+
+// Update invokes BeforeUpdate on the instances attached to
+// root, applies fn and then invokes AfterUpdate.
+func (l *Lifecycle) Update(root Component, fn func()) {
+	insts := l.instances[root]
+	for _, inst := range slices.Backward(insts) {
+		if u, ok := inst.(Updater); ok {
+			u.BeforeUpdate()
+		}
+	}
+	fn()
+	for _, inst := range insts {
+		if u, ok := inst.(Updater); ok {
+			u.AfterUpdate()
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type node struct {
+	name     string
+	children []Component
+}
+
+func (n *node) Name() string                     { return n.name }
+func (n *node) Attributes() map[string]any       { return nil }
+func (n *node) SetAttribute(key string, val any) {}
+func (n *node) Children() []Component            { return n.children }
+
+type hooks struct {
+	name  string
+	calls *[]string
+}
+
+func (h *hooks) OnMount()      { *h.calls = append(*h.calls, h.name+".OnMount") }
+func (h *hooks) OnUnmount()    { *h.calls = append(*h.calls, h.name+".OnUnmount") }
+func (h *hooks) BeforeUpdate() { *h.calls = append(*h.calls, h.name+".BeforeUpdate") }
+func (h *hooks) AfterUpdate()  { *h.calls = append(*h.calls, h.name+".AfterUpdate") }
+
+func TestLifecycle(t *testing.T) {
+	var calls []string
+	child := &node{name: "span"}
+	root := &node{name: "div", children: []Component{child}}
+
+	var l Lifecycle
+	l.Attach(child, &hooks{"child", &calls})
+	// A component whose output is solely another component
+	// shares its root with that component
+	l.Attach(root, &hooks{"inner", &calls})
+	l.Attach(root, &hooks{"outer", &calls})
+	l.Attach(root, struct{}{})
+
+	l.Mount(root)
+	assert.Equal(t, []string{"child.OnMount", "inner.OnMount", "outer.OnMount"}, calls)
+
+	calls = nil
+	l.Update(root, func() { calls = append(calls, "update") })
+	assert.Equal(t, []string{"outer.BeforeUpdate", "inner.BeforeUpdate", "update", "inner.AfterUpdate", "outer.AfterUpdate"}, calls)
+
+	calls = nil
+	l.Unmount(root)
+	assert.Equal(t, []string{"outer.OnUnmount", "inner.OnUnmount", "child.OnUnmount"}, calls)
+
+	calls = nil
+	l.Mount(root)
+	assert.Empty(t, calls, "expected unmounted instances to be detached")
+}
@@ -0,0 +1,245 @@
+package render
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/tifye/flamingo/reactive"
+)
+
+// Reactive is embedded by the compiler in component structs to
+// update their output as their fields change. Parts of the output
+// are bound to the fields they depend on as the output is built.
+// Changed fields are then marked with Invalidate, and the parts
+// of the output depending on them are set again by the next
+// update, scheduled with the renderer.
+//
+// Bound output is set by a reactive.Effect of the Runtime of the
+// renderer, so output reading signals of that Runtime is also
+// updated as soon as one of them changes.
+//
+// Blocks of the output, bound with Block, are built anew as they
+// are updated, and the output bound as they are built is then
+// bound no more, be it that of the component or of another, such
+// as the content the component passes to the slots of another.
+type Reactive struct {
+	renderer Renderer
+	runtime  *reactive.Runtime // runtime of instances without a renderer
+	root     Component
+	order    uint64 // creation order of the instance
+	bindings []*binding
+	live     int // number of bindings left by the last pruning, see bind
+	dirty    map[string]bool
+	all      bool // whether all fields are invalidated
+	stale    bool // whether signals read by bindings changed
+	applying bool // whether bindings are being applied
+}
+
+type binding struct {
+	deps   []string // fields depended on, nil for all
+	effect *reactive.Effect
+	stale  bool // whether signals read by the binding changed
+}
+
+// stopped reports whether b is stopped, which it is once the
+// block whose effect owns that of b is built anew.
+func (b *binding) stopped() bool {
+	return b.effect != nil && b.effect.Stopped()
+}
+
+// instances counts the Reactives created by NewReactive.
+var instances atomic.Uint64
+
+// NewReactive returns a Reactive scheduling its updates with
+// renderer. Instances must be created before the instances of
+// their children. NewReactive is called by compiled components.
+func NewReactive(renderer Renderer) Reactive {
+	return Reactive{
+		renderer: renderer,
+		order:    instances.Add(1),
+	}
+}
+
+// Bind calls set, which sets part of the output of the component,
+// and calls it again on updates of the fields deps or of the
+// signals it reads. Nil deps are for output depending on the whole
+// component. Bind is called by compiled components.
+func (r *Reactive) Bind(deps []string, set func()) {
+	r.bind(&binding{deps: deps}, set)
+}
+
+func (r *Reactive) bind(b *binding, set func()) {
+	// Bindings are stopped by blocks which may be those of
+	// other instances, so the stopped ones are dropped as
+	// the bindings double
+	if len(r.bindings) >= 2*r.live {
+		r.prune()
+	}
+	r.bindings = append(r.bindings, b)
+
+	applying := r.applying
+	r.applying = true
+	defer func() {
+		r.applying = applying
+	}()
+
+	b.effect = reactive.NewEffect(r.Runtime(), func() {
+		if r.applying || r.renderer == nil {
+			set()
+			return
+		}
+		// Applied by the next update, which runs the
+		// effect again tracking the signals read anew
+		b.stale, r.stale = true, true
+		r.renderer.Schedule(r)
+	})
+}
+
+// prune drops the stopped bindings.
+func (r *Reactive) prune() {
+	r.bindings = slices.DeleteFunc(r.bindings, (*binding).stopped)
+	r.live = len(r.bindings)
+}
+
+// Block builds a block of the output of the component, a range of
+// the children of parent, with build, which appends the components
+// of the block to the component it is passed. The block is built
+// anew on updates of the fields deps or of the signals build reads,
+// the components built being reconciled with the current ones, see
+// Renderer.Reconcile. Output bound as the block is built is bound
+// until it is built anew. Block is called by compiled components.
+func (r *Reactive) Block(parent Component, deps []string, build func(parent Component)) {
+	for _, c := range r.block(deps, build) {
+		r.renderer.Append(parent, c)
+	}
+}
+
+// RootBlock is like Block for a block at the top level of the
+// output, returning the components built, which are to be added
+// to the roots of the output of the component. RootBlock is called
+// by compiled components.
+func (r *Reactive) RootBlock(deps []string, build func(parent Component)) []Component {
+	return r.block(deps, build)
+}
+
+// block binds the building of a block, returning the components
+// built first, which are to be added to the parent of the block.
+func (r *Reactive) block(deps []string, build func(parent Component)) []Component {
+	// The block is kept between two empty texts
+	// as it moves along with its parent
+	start, end := r.renderer.NewText(""), r.renderer.NewText("")
+	var built []Component
+
+	b := &binding{deps: deps}
+	r.bind(b, func() {
+		// The effects bound as the block was built last,
+		// owned by the effect of b, are stopped by now
+		r.prune()
+
+		block := r.renderer.NewComponent("")
+		build(block)
+
+		parent := Parent(end)
+		if parent == nil {
+			// Not yet added to its parent
+			built = slices.Concat([]Component{start}, block.Children(), []Component{end})
+			return
+		}
+		children := parent.Children()
+		i, j := slices.Index(children, start), slices.Index(children, end)
+		r.renderer.Reconcile(parent, slices.Concat(children[:i+1], block.Children(), children[j:]))
+	})
+	return built
+}
+
+// Runtime returns the reactive.Runtime of the output of the
+// component, that of its renderer, with which the signals the
+// output reads are to be created.
+func (r *Reactive) Runtime() *reactive.Runtime {
+	if r.renderer != nil {
+		return r.renderer.Runtime()
+	}
+	if r.runtime == nil {
+		r.runtime = reactive.NewRuntime()
+	}
+	return r.runtime
+}
+
+// SetRoot sets the component the output of the component is
+// rooted at, so that updates invoke the Updater hooks attached
+// to it, and the bound output stops being set once root is
+// removed. SetRoot is called by compiled components.
+func (r *Reactive) SetRoot(root Component) {
+	r.root = root
+	if r.renderer != nil {
+		r.renderer.Attach(root, reactiveRoot{r})
+	}
+}
+
+// reactiveRoot stops the effects of the
+// Reactive whose output it is attached to.
+type reactiveRoot struct {
+	r *Reactive
+}
+
+func (root reactiveRoot) OnUnmount() {
+	for _, b := range root.r.bindings {
+		b.effect.Stop()
+	}
+}
+
+// Invalidate marks fields of the component as changed, or all
+// of them if none are given, and schedules an update of the
+// output depending on them.
+func (r *Reactive) Invalidate(fields ...string) {
+	if len(fields) == 0 {
+		r.all = true
+	} else {
+		if r.dirty == nil {
+			r.dirty = make(map[string]bool)
+		}
+		for _, f := range fields {
+			r.dirty[f] = true
+		}
+	}
+
+	if r.renderer != nil {
+		r.renderer.Schedule(r)
+	}
+}
+
+// Update sets the output depending on the invalidated fields or
+// changed signals again now, in the order it was bound. It does
+// nothing if nothing changed since the last update.
+func (r *Reactive) Update() {
+	if !r.all && !r.stale && len(r.dirty) == 0 {
+		return
+	}
+	dirty, all := r.dirty, r.all
+	r.dirty, r.all, r.stale = nil, false, false
+
+	apply := func() {
+		applying := r.applying
+		r.applying = true
+		defer func() {
+			r.applying = applying
+		}()
+
+		r.prune()
+		// Blocks built anew change the bindings
+		for _, b := range slices.Clone(r.bindings) {
+			if b.stopped() {
+				continue
+			}
+			if all || b.stale || b.deps == nil || slices.ContainsFunc(b.deps, func(dep string) bool { return dirty[dep] }) {
+				b.stale = false
+				b.effect.Run()
+			}
+		}
+	}
+	if r.renderer == nil || r.root == nil {
+		apply()
+		return
+	}
+	r.renderer.Update(r.root, apply)
+}
@@ -0,0 +1,189 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tifye/flamingo/reactive"
+)
+
+// updateRecorder is a Renderer recording calls to Update.
+type updateRecorder struct {
+	Renderer
+	Lifecycle
+	Scheduler
+	roots []Component
+}
+
+func (u *updateRecorder) Attach(root Component, instance any) {
+	u.Lifecycle.Attach(root, instance)
+}
+
+func (u *updateRecorder) Update(root Component, fn func()) {
+	u.roots = append(u.roots, root)
+	fn()
+}
+
+func (u *updateRecorder) Schedule(r *Reactive) {
+	u.Scheduler.Schedule(r)
+}
+
+func (u *updateRecorder) Runtime() *reactive.Runtime {
+	return u.Scheduler.Runtime()
+}
+
+func TestReactive(t *testing.T) {
+	var r Reactive
+	var sets []string
+	bind := func(name string, deps ...string) {
+		r.Bind(deps, func() { sets = append(sets, name) })
+	}
+	bind("count", "count")
+	bind("both", "count", "label")
+	bind("whole")
+	assert.Equal(t, []string{"count", "both", "whole"}, sets, "expected bound output to be set")
+
+	sets = nil
+	r.Update()
+	assert.Empty(t, sets, "expected no updates without invalidated fields")
+
+	r.Invalidate("label")
+	r.Update()
+	assert.Equal(t, []string{"both", "whole"}, sets)
+
+	sets = nil
+	r.Update()
+	assert.Empty(t, sets, "expected updates to clear invalidated fields")
+
+	r.Invalidate()
+	r.Update()
+	assert.Equal(t, []string{"count", "both", "whole"}, sets)
+}
+
+func TestReactiveScheduled(t *testing.T) {
+	renderer := &updateRecorder{}
+	r := NewReactive(renderer)
+	count := reactive.NewSignal(r.Runtime(), 1)
+	var sets []string
+	r.Bind([]string{"label"}, func() { sets = append(sets, "label") })
+	r.Bind([]string{}, func() {
+		count.Get()
+		sets = append(sets, "count")
+	})
+	root := &node{name: "div"}
+	r.SetRoot(root)
+
+	sets = nil
+	r.Invalidate("label")
+	count.Set(2)
+	count.Set(3)
+	assert.Empty(t, sets, "expected updates to be deferred to the next flush")
+
+	renderer.Flush()
+	assert.Equal(t, []string{"label", "count"}, sets)
+	assert.Equal(t, []Component{root}, renderer.roots, "expected updates through the renderer")
+
+	sets = nil
+	count.Set(4)
+	renderer.Flush()
+	assert.Equal(t, []string{"count"}, sets, "expected signals read by the last update to be tracked")
+
+	sets = nil
+	renderer.Unmount(root)
+	count.Set(5)
+	r.Invalidate()
+	renderer.Flush()
+	assert.Empty(t, sets, "expected output not to be set once removed")
+}
+
+// treeRenderer is a Renderer building trees of keyed components.
+type treeRenderer struct {
+	updateRecorder
+	tree
+}
+
+func (r *treeRenderer) NewComponent(name string) Component {
+	return &keyed{name: name}
+}
+
+func (r *treeRenderer) NewText(text string) Component {
+	c := &keyed{name: TextName}
+	c.SetAttribute(TextAttribute, text)
+	return c
+}
+
+func (r *treeRenderer) Append(parent Component, child Component) {
+	Link(parent, child, nil)
+}
+
+func (r *treeRenderer) Reconcile(parent Component, children []Component) []Component {
+	return Reconcile(&r.tree, parent, children)
+}
+
+func TestReactiveBlock(t *testing.T) {
+	renderer := &treeRenderer{}
+	r := NewReactive(renderer)
+	items := []string{"a"}
+	labels := 0
+
+	ul := &keyed{name: "ul"}
+	Link(ul, &keyed{name: "first"}, nil)
+	r.Block(ul, []string{"items"}, func(parent Component) {
+		for _, item := range items {
+			li := &keyed{name: item}
+			li.SetAttribute(KeyAttribute, item)
+			Link(parent, li, nil)
+			r.Bind([]string{"label"}, func() { labels++ })
+		}
+	})
+	Link(ul, &keyed{name: "last"}, nil)
+	assert.Equal(t, "first #text a #text last", names(ul))
+	a := ul.Children()[2]
+
+	items = []string{"b", "a"}
+	r.Invalidate("items")
+	r.Update()
+	assert.Equal(t, "first #text b a #text last", names(ul), "expected the block to be built anew in place")
+	assert.Same(t, a, ul.Children()[3], "expected components with the same key to be kept")
+
+	labels = 0
+	r.Invalidate("label")
+	r.Update()
+	assert.Equal(t, 2, labels, "expected the output bound by previous builds to be bound no more")
+
+	items = nil
+	r.Invalidate()
+	r.Update()
+	assert.Equal(t, "first #text #text last", names(ul))
+	assert.Equal(t, 2, renderer.removes)
+}
+
+func TestReactiveBlockOtherInstance(t *testing.T) {
+	renderer := &treeRenderer{}
+	outer, inner := NewReactive(renderer), NewReactive(renderer)
+	open := true
+	labels := 0
+
+	div := &keyed{name: "div"}
+	inner.Block(div, []string{"open"}, func(parent Component) {
+		if open {
+			// Content passed to a slot by outer
+			p := &keyed{name: "p"}
+			Link(parent, p, nil)
+			outer.Bind(nil, func() { labels++ })
+		}
+	})
+	// Closed and opened again five times
+	for range 10 {
+		open = !open
+		inner.Invalidate("open")
+		inner.Update()
+	}
+	assert.Equal(t, "#text p #text", names(div))
+
+	labels = 0
+	outer.Invalidate()
+	outer.Update()
+	assert.Equal(t, 1, labels, "expected the output bound by another instance as the block was built to be bound no more")
+	assert.Len(t, outer.bindings, 1, "expected the stopped bindings to be dropped")
+}
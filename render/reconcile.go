@@ -0,0 +1,161 @@
+package render
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// KeyAttribute is the attribute identifying a child
+// component across calls to Renderer.Reconcile.
+const KeyAttribute = "key"
+
+// A Tree rearranges the children of components. Renderers
+// implement it to reconcile children with Reconcile.
+type Tree interface {
+	// Insert moves child, or inserts it, into the children of
+	// parent before the child before, or at their end if before
+	// is nil.
+	Insert(parent Component, child Component, before Component)
+
+	// Remove detaches child from its parent, unmounting it first.
+	Remove(child Component)
+}
+
+// Reconcile rearranges the children of parent in t to be the
+// components children, and returns the resulting children.
+//
+// A current child is kept in place of a component of children if
+// it is the same component or if both have the same comparable key
+// attribute. In the latter case the kept child is given the
+// attributes and, reconciled in turn, the children of the component
+// it is kept in place of, which is discarded and must not be used
+// anymore. The other current children are removed and the other
+// components of children inserted. Moves are kept to a minimum by
+// leaving the longest subsequence of kept children already in the
+// right order in place.
+func Reconcile(t Tree, parent Component, children []Component) []Component {
+	old := slices.Clone(parent.Children())
+	same := make(map[Component]int, len(old))
+	keys := make(map[any]int, len(old))
+	for i, c := range old {
+		same[c] = i
+		if key, ok := keyOf(c); ok {
+			if _, dup := keys[key]; !dup {
+				keys[key] = i
+			}
+		}
+	}
+
+	result := make([]Component, len(children))
+	// sources holds the index in old of the
+	// component kept for each child, or -1
+	sources := make([]int, len(children))
+	kept := make([]bool, len(old))
+	for i, c := range children {
+		result[i], sources[i] = c, -1
+
+		j, ok := same[c]
+		if !ok {
+			key, hasKey := keyOf(c)
+			if !hasKey {
+				continue
+			}
+			if j, ok = keys[key]; !ok {
+				continue
+			}
+		}
+		if kept[j] {
+			continue
+		}
+
+		kept[j] = true
+		result[i], sources[i] = old[j], j
+	}
+
+	for i, c := range old {
+		if !kept[i] {
+			t.Remove(c)
+		}
+	}
+
+	stay := longestIncreasing(sources)
+	var before Component
+	for i := len(result) - 1; i >= 0; i-- {
+		if !stay[i] {
+			t.Insert(parent, result[i], before)
+		}
+		before = result[i]
+	}
+
+	for i, c := range children {
+		if result[i] != c {
+			patch(t, result[i], c)
+		}
+	}
+	return result
+}
+
+// patch gives kept the attributes and children of c.
+// Attributes which c does not have are left as they are.
+func patch(t Tree, kept Component, c Component) {
+	attrs := c.Attributes()
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		kept.SetAttribute(key, attrs[key])
+	}
+	Reconcile(t, kept, slices.Clone(c.Children()))
+}
+
+// keyOf returns the key attribute of c if it is set to a
+// comparable value.
+func keyOf(c Component) (any, bool) {
+	key, ok := c.Attributes()[KeyAttribute]
+	if !ok || key == nil || !reflect.TypeOf(key).Comparable() {
+		return nil, false
+	}
+	return key, true
+}
+
+// longestIncreasing reports, for every element of seq, whether it
+// is part of a longest strictly increasing subsequence of the
+// elements of seq which are not negative.
+func longestIncreasing(seq []int) []bool {
+	// tails[k] is the index of the smallest element ending an
+	// increasing subsequence of length k+1
+	tails := make([]int, 0, len(seq))
+	prev := make([]int, len(seq))
+	for i, v := range seq {
+		if v < 0 {
+			continue
+		}
+
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if seq[tails[mid]] < v {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+
+		prev[i] = -1
+		if lo > 0 {
+			prev[i] = tails[lo-1]
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+
+	in := make([]bool, len(seq))
+	if len(tails) == 0 {
+		return in
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		in[i] = true
+	}
+	return in
+}
@@ -0,0 +1,152 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type keyed struct {
+	Links
+	name  string
+	attrs map[string]any
+}
+
+func (k *keyed) Name() string               { return k.name }
+func (k *keyed) Attributes() map[string]any { return k.attrs }
+func (k *keyed) SetAttribute(key string, val any) {
+	if k.attrs == nil {
+		k.attrs = make(map[string]any)
+	}
+	k.attrs[key] = val
+}
+
+// tree is a Tree recording the operations applied to it.
+type tree struct {
+	inserts int
+	removes int
+}
+
+func (t *tree) Insert(parent Component, child Component, before Component) {
+	Link(parent, child, before)
+	t.inserts++
+}
+
+func (t *tree) Remove(child Component) {
+	Unlink(child)
+	t.removes++
+}
+
+// names returns the names of the children of c.
+func names(c Component) string {
+	names := make([]string, 0, len(c.Children()))
+	for _, child := range c.Children() {
+		names = append(names, child.Name())
+	}
+	return strings.Join(names, " ")
+}
+
+// components returns a keyed component for every key, named
+// after the key and prefix, or an unkeyed one for a key "_".
+func components(prefix string, keys ...string) []Component {
+	comps := make([]Component, 0, len(keys))
+	for _, key := range keys {
+		c := &keyed{name: prefix + key}
+		if key != "_" {
+			c.SetAttribute(KeyAttribute, key)
+		}
+		comps = append(comps, c)
+	}
+	return comps
+}
+
+// parentOf returns a component with the children comps.
+func parentOf(comps []Component) Component {
+	p := &keyed{name: "parent"}
+	for _, c := range comps {
+		Link(p, c, nil)
+	}
+	return p
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		old      []string
+		new      []string
+		expected string
+		inserts  int
+		removes  int
+	}{
+		{[]string{}, []string{"a", "b"}, "new.a new.b", 2, 0},
+		{[]string{"a", "b"}, []string{}, "", 0, 2},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}, "old.a old.b old.c", 0, 0},
+		{[]string{"a", "b", "c"}, []string{"c", "b", "a"}, "old.c old.b old.a", 2, 0},
+		{[]string{"a", "b", "c", "d", "e"}, []string{"a", "d", "c", "b", "e"}, "old.a old.d old.c old.b old.e", 2, 0},
+		{[]string{"a", "b", "c", "d"}, []string{"d", "a", "b", "c"}, "old.d old.a old.b old.c", 1, 0},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c", "y"}, "old.a new.x old.c new.y", 2, 1},
+		{[]string{"a", "_", "b"}, []string{"b", "_", "a"}, "old.b new._ old.a", 2, 1},
+		{[]string{"a", "a"}, []string{"a", "a"}, "old.a new.a", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v to %v", tt.old, tt.new), func(t *testing.T) {
+			p := parentOf(components("old.", tt.old...))
+			tr := &tree{}
+
+			result := Reconcile(tr, p, components("new.", tt.new...))
+			assert.Equal(t, tt.expected, names(p))
+			assert.Equal(t, p.Children(), result)
+			assert.Equal(t, tt.inserts, tr.inserts, "inserts")
+			assert.Equal(t, tt.removes, tr.removes, "removes")
+		})
+	}
+
+	t.Run("same components", func(t *testing.T) {
+		old := components("old.", "_", "_", "_")
+		p := parentOf(old)
+		tr := &tree{}
+
+		children := []Component{old[2], old[0], old[1]}
+		result := Reconcile(tr, p, children)
+		assert.Equal(t, children, result)
+		assert.Equal(t, children, p.Children())
+		assert.Equal(t, 1, tr.inserts)
+		assert.Equal(t, 0, tr.removes)
+	})
+
+	t.Run("patch kept components", func(t *testing.T) {
+		old := components("old.", "a")
+		old[0].SetAttribute("class", "old")
+		Link(old[0], &keyed{name: "old.child"}, nil)
+		p := parentOf(old)
+
+		children := components("new.", "a")
+		children[0].SetAttribute("class", "new")
+		child := &keyed{name: "new.child"}
+		Link(children[0], child, nil)
+
+		result := Reconcile(&tree{}, p, children)
+		assert.Equal(t, old, result)
+		assert.Equal(t, "new", old[0].Attributes()["class"])
+		assert.Equal(t, []Component{child}, old[0].Children(), "expected the children of the new component")
+		assert.Same(t, old[0], Parent(child))
+		assert.Empty(t, children[0].Children())
+	})
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	tests := []struct {
+		seq      []int
+		expected []bool
+	}{
+		{[]int{}, []bool{}},
+		{[]int{-1, -1}, []bool{false, false}},
+		{[]int{0, 1, 2}, []bool{true, true, true}},
+		{[]int{2, 1, 0}, []bool{false, false, true}},
+		{[]int{3, 0, -1, 1, 4, 2}, []bool{false, true, false, true, false, true}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, longestIncreasing(tt.seq), "%v", tt.seq)
+	}
+}
@@ -1,5 +1,7 @@
 package render
 
+import "github.com/tifye/flamingo/reactive"
+
 type Component interface {
 	Name() string
 	Attributes() map[string]any
@@ -7,8 +9,50 @@ type Component interface {
 	Children() []Component
 }
 
+// Text components, created by Renderer.NewText, are named
+// TextName and hold their text in the TextAttribute.
+const (
+	TextName      = "#text"
+	TextAttribute = "text"
+)
+
 type Renderer interface {
 	Render(comps ...Component)
 	Append(parent Component, child Component)
 	NewComponent(name string) Component
+
+	// NewText returns a component holding text, appended among
+	// the other children of its parent in place. Its text is
+	// changed by setting its TextAttribute to any value, which
+	// is formatted as by fmt.Sprint. Text has no children.
+	NewText(text string) Component
+
+	// Remove detaches comp from its parent, or the document
+	// if it was rendered as a root, unmounting it first.
+	Remove(comp Component)
+
+	// Reconcile replaces the children of parent with children,
+	// keeping current children in place of those with the same
+	// key attribute so their state is preserved, and returns
+	// the resulting children. See the Reconcile function.
+	Reconcile(parent Component, children []Component) []Component
+
+	// Attach registers instance as the component instance
+	// whose output is rooted at root. Lifecycle hooks
+	// implemented by instance are invoked as root is
+	// mounted, updated and removed.
+	Attach(root Component, instance any)
+
+	// Update applies fn, which changes the components rooted
+	// at root, invoking the Updater hooks attached to root
+	// around it.
+	Update(root Component, fn func())
+
+	// Schedule queues the update of the component
+	// instance r, see Scheduler.
+	Schedule(r *Reactive)
+
+	// Runtime returns the reactive.Runtime of the
+	// output of the component instances rendered.
+	Runtime() *reactive.Runtime
 }
@@ -0,0 +1,104 @@
+package render
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/tifye/flamingo/reactive"
+)
+
+// A Clock is a source of frames, such as the
+// browser's animation frames.
+type Clock interface {
+	// RequestFrame arranges for fn to be
+	// called once, at the next frame.
+	RequestFrame(fn func())
+}
+
+// maxFlushRounds is the number of times updates may schedule
+// further updates within a single flush before giving up.
+const maxFlushRounds = 100
+
+// Scheduler queues the updates of component instances so that
+// they are applied together at the next frame of its Clock.
+// Renderers embed it to implement Schedule.
+//
+// A component instance is updated at most once per flush, no
+// matter how often it is scheduled, and parents are updated
+// before their children. Updates scheduled while flushing are
+// applied by the same flush.
+//
+// The Scheduler also holds the reactive.Runtime of the output
+// of the component instances it updates, so that renderers
+// used by different goroutines share no state.
+type Scheduler struct {
+	// Clock requests the frames updates are flushed at.
+	// If nil, updates are only flushed by calls to Flush.
+	Clock Clock
+
+	runtime   reactive.Runtime
+	queue     []*Reactive
+	queued    map[*Reactive]bool
+	requested bool // whether a frame is requested
+	flushing  bool
+}
+
+// Runtime returns the reactive.Runtime of the
+// output of the instances the Scheduler updates.
+func (s *Scheduler) Runtime() *reactive.Runtime {
+	return &s.runtime
+}
+
+// Schedule queues the update of r to the next frame.
+func (s *Scheduler) Schedule(r *Reactive) {
+	if s.queued[r] {
+		return
+	}
+	if s.queued == nil {
+		s.queued = make(map[*Reactive]bool)
+	}
+	s.queued[r] = true
+	s.queue = append(s.queue, r)
+
+	if s.Clock != nil && !s.requested && !s.flushing {
+		s.requested = true
+		s.Clock.RequestFrame(s.frame)
+	}
+}
+
+func (s *Scheduler) frame() {
+	s.requested = false
+	s.Flush()
+}
+
+// Flush applies the queued updates now, parents
+// before children. It panics if updates keep
+// scheduling further updates.
+func (s *Scheduler) Flush() {
+	if s.flushing {
+		return
+	}
+	s.flushing = true
+	defer func() {
+		s.flushing = false
+	}()
+
+	for rounds := 0; len(s.queue) > 0; rounds++ {
+		if rounds == maxFlushRounds {
+			s.queue, s.queued = nil, nil
+			panic("render: updates keep scheduling updates")
+		}
+
+		queue := s.queue
+		s.queue = nil
+		// Instances are created before the
+		// instances of their children
+		slices.SortFunc(queue, func(a, b *Reactive) int {
+			return cmp.Compare(a.order, b.order)
+		})
+		for _, r := range queue {
+			delete(s.queued, r)
+			r.Update()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// manualClock is a Clock whose frames are run by calling tick.
+type manualClock struct {
+	frames []func()
+}
+
+func (c *manualClock) RequestFrame(fn func()) {
+	c.frames = append(c.frames, fn)
+}
+
+func (c *manualClock) tick() {
+	frames := c.frames
+	c.frames = nil
+	for _, fn := range frames {
+		fn()
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	clock := &manualClock{}
+	renderer := &updateRecorder{Scheduler: Scheduler{Clock: clock}}
+
+	var updates []string
+	instance := func(name string) *Reactive {
+		r := NewReactive(renderer)
+		r.Bind(nil, func() { updates = append(updates, name) })
+		return &r
+	}
+	parent := instance("parent")
+	child := instance("child")
+	updates = nil
+
+	child.Invalidate()
+	parent.Invalidate()
+	child.Invalidate()
+	assert.Empty(t, updates, "expected updates to wait for the next frame")
+	assert.Len(t, clock.frames, 1, "expected a single frame to be requested")
+
+	clock.tick()
+	assert.Equal(t, []string{"parent", "child"}, updates, "expected parents to be updated before children, once")
+
+	t.Run("scheduled while flushing", func(t *testing.T) {
+		r := NewReactive(renderer)
+		r.Bind([]string{"parent"}, func() {
+			updates = append(updates, "scheduling")
+			child.Invalidate()
+		})
+		clock.tick()
+		updates = nil
+
+		r.Invalidate("parent")
+		clock.tick()
+		assert.Equal(t, []string{"scheduling", "child"}, updates, "expected the update to be applied by the same flush")
+		assert.Empty(t, clock.frames, "expected no frame to be requested while flushing")
+	})
+
+	t.Run("manual flush", func(t *testing.T) {
+		var manual updateRecorder
+		r := NewReactive(&manual)
+		r.Bind(nil, func() { updates = append(updates, "manual") })
+		updates = nil
+
+		r.Invalidate()
+		assert.Empty(t, updates)
+		manual.Flush()
+		assert.Equal(t, []string{"manual"}, updates)
+	})
+
+	t.Run("runaway updates", func(t *testing.T) {
+		var manual updateRecorder
+		r := NewReactive(&manual)
+		r.Bind(nil, func() { r.Invalidate() })
+
+		r.Invalidate()
+		assert.Panics(t, manual.Flush)
+		assert.Empty(t, manual.queue, "expected the queue to be dropped")
+	})
+}
@@ -0,0 +1,9 @@
+package render
+
+// A Slot renders markup passed to a component,
+// appending the components it creates to parent.
+type Slot func(parent Component)
+
+// Slots holds the markup passed to a component for each
+// of its slots by name, the default slot being named "".
+type Slots map[string]Slot
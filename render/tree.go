@@ -0,0 +1,65 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Links holds the parent and children of a component. Renderers
+// embed it in their components, which are then moved among the
+// children of other components with Link and Unlink.
+type Links struct {
+	parent   Component
+	children []Component
+}
+
+func (l *Links) Children() []Component {
+	return l.children
+}
+
+func (l *Links) links() *Links {
+	return l
+}
+
+// linked is implemented by the components embedding Links.
+type linked interface {
+	links() *Links
+}
+
+// Parent returns the component c is a child of,
+// or nil if it is not a child of any.
+func Parent(c Component) Component {
+	return linksOf(c).parent
+}
+
+// Link moves child, or adds it, into the children of parent
+// before the child before, or at their end if it is nil.
+func Link(parent Component, child Component, before Component) {
+	Unlink(child)
+	p := linksOf(parent)
+	i := len(p.children)
+	if before != nil {
+		i = slices.Index(p.children, before)
+	}
+	p.children = slices.Insert(p.children, i, child)
+	linksOf(child).parent = parent
+}
+
+// Unlink removes child from the children of its parent, if any.
+func Unlink(child Component) {
+	c := linksOf(child)
+	if c.parent == nil {
+		return
+	}
+	p := linksOf(c.parent)
+	p.children = slices.DeleteFunc(p.children, func(cc Component) bool { return cc == child })
+	c.parent = nil
+}
+
+func linksOf(c Component) *Links {
+	l, ok := c.(linked)
+	if !ok {
+		panic(fmt.Sprintf("render: component %T does not embed Links", c))
+	}
+	return l.links()
+}
@@ -0,0 +1,30 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLink(t *testing.T) {
+	p, q := &keyed{name: "p"}, &keyed{name: "q"}
+	a, b, c := &keyed{name: "a"}, &keyed{name: "b"}, &keyed{name: "c"}
+
+	Link(p, a, nil)
+	Link(p, c, nil)
+	Link(p, b, c)
+	assert.Equal(t, "a b c", names(p))
+	assert.Same(t, p, Parent(b))
+
+	Link(q, b, nil)
+	assert.Equal(t, "a c", names(p), "expected b to be moved out of p")
+	assert.Equal(t, "b", names(q))
+	assert.Same(t, q, Parent(b))
+
+	Unlink(a)
+	Unlink(a)
+	assert.Equal(t, "c", names(p))
+	assert.Nil(t, Parent(a))
+
+	assert.Panics(t, func() { Parent(&node{}) }, "expected components not embedding Links to be rejected")
+}
@@ -28,6 +28,14 @@ const (
 	GO_EXPRESSION
 	GO_CODE
 	CODE_FENCE
+
+	BLOCK_OPEN     // {#
+	BLOCK_CONTINUE // {:
+	BLOCK_CLOSE    // {/
+	RIGHT_BRACE
+	IF
+	ELSE
+	EACH
 )
 
 type Token struct {
@@ -22,11 +22,18 @@ func _() {
 	_ = x[GO_EXPRESSION-11]
 	_ = x[GO_CODE-12]
 	_ = x[CODE_FENCE-13]
+	_ = x[BLOCK_OPEN-14]
+	_ = x[BLOCK_CONTINUE-15]
+	_ = x[BLOCK_CLOSE-16]
+	_ = x[RIGHT_BRACE-17]
+	_ = x[IF-18]
+	_ = x[ELSE-19]
+	_ = x[EACH-20]
 }
 
-const _TokenType_name = "ERROREOFLEFT_CHEVRONRIGHT_CHEVRONSLASHIDENTASSIGNQUOTECOLONONTEXTGO_EXPRESSIONGO_CODECODE_FENCE"
+const _TokenType_name = "ERROREOFLEFT_CHEVRONRIGHT_CHEVRONSLASHIDENTASSIGNQUOTECOLONONTEXTGO_EXPRESSIONGO_CODECODE_FENCEBLOCK_OPENBLOCK_CONTINUEBLOCK_CLOSERIGHT_BRACEIFELSEEACH"
 
-var _TokenType_index = [...]uint8{0, 5, 8, 20, 33, 38, 43, 49, 54, 59, 61, 65, 78, 85, 95}
+var _TokenType_index = [...]uint8{0, 5, 8, 20, 33, 38, 43, 49, 54, 59, 61, 65, 78, 85, 95, 105, 119, 130, 141, 143, 147, 151}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
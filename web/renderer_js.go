@@ -1,6 +1,7 @@
 package web
 
 import (
+	"fmt"
 	"log"
 	"strings"
 	"syscall/js"
@@ -9,11 +10,18 @@ import (
 )
 
 type WebComponent struct {
-	name  string
-	text  string
-	comps []render.Component
-	attrs map[string]any
-	el    *js.Value
+	render.Links
+	name      string
+	text      string // text of text components
+	attrs     map[string]any
+	el        *js.Value
+	listeners map[string]listener // by directive
+}
+
+// listener is an event listener registered for a directive.
+type listener struct {
+	event string
+	fn    js.Func
 }
 
 func (c *WebComponent) Element() *js.Value {
@@ -26,31 +34,97 @@ func (c *WebComponent) SetAttribute(key string, val any) {
 	}
 	c.attrs[key] = val
 
+	if c.name == render.TextName {
+		if key == render.TextAttribute {
+			c.text = fmt.Sprint(val)
+			if c.el != nil {
+				c.el.Set("nodeValue", c.text)
+			}
+		}
+		return
+	}
+
 	if c.el != nil {
-		switch key {
-		case "innerText", "value":
-			c.el.Set(key, val)
+		c.apply(key, val)
+	}
+}
+
+// apply sets the attribute key of the element of c to val.
+func (c *WebComponent) apply(key string, val any) {
+	switch {
+	case key == render.KeyAttribute:
+		// Keys only identify components when reconciling
+	case strings.HasPrefix(key, "on:"), strings.HasPrefix(key, "bind:"):
+		c.listen(key, val)
+	case key == "innerText", key == "value":
+		c.el.Set(key, val)
+	default:
+		c.el.Call("setAttribute", key, val)
+	}
+}
+
+// listen registers the event listener of the directive key calling
+// val on the element of c, in place of the one registered before,
+// which is released.
+func (c *WebComponent) listen(key string, val any) {
+	var l listener
+	if event, ok := strings.CutPrefix(key, "on:"); ok {
+		valFunc, ok := val.(func())
+		if !ok {
+			log.Fatal("only func allowed for eventlisteners")
+		}
+
+		l.event = event
+		l.fn = js.FuncOf(func(this js.Value, args []js.Value) any {
+			valFunc()
+			return nil
+		})
+	} else {
+		valFunc, ok := val.(func(val any))
+		if !ok {
+			log.Fatal("expected func(val any) when binding")
+		}
+
+		attr := strings.TrimPrefix(key, "bind:")
+		switch attr {
+		case "value":
+			l.event = "input"
+			l.fn = js.FuncOf(func(this js.Value, args []js.Value) any {
+				valFunc(c.el.Get("value").String())
+				return nil
+			})
 		default:
-			c.el.Call("setAttribute", key, val)
+			panic("bind to " + attr + "not implemented")
 		}
 	}
+
+	if old, ok := c.listeners[key]; ok {
+		c.el.Call("removeEventListener", old.event, old.fn)
+		old.fn.Release()
+	}
+	if c.listeners == nil {
+		c.listeners = make(map[string]listener)
+	}
+	c.listeners[key] = l
+	c.el.Call("addEventListener", l.event, l.fn)
 }
 
 func (c *WebComponent) Name() string {
 	return c.name
 }
 
-func (c *WebComponent) Children() []render.Component {
-	return c.comps
-}
-
 func (c *WebComponent) Attributes() map[string]any {
 	return c.attrs
 }
 
 type DOMRenderer struct {
-	doc  js.Value
-	body js.Value
+	render.Lifecycle
+	render.Scheduler
+
+	doc js.Value
+	// body holds the rendered roots as its children,
+	// so they are rearranged like those of any component
+	body *WebComponent
 }
 
 func NewDOMRenderer() *DOMRenderer {
@@ -58,81 +132,64 @@ func NewDOMRenderer() *DOMRenderer {
 	body := doc.Get("body")
 
 	return &DOMRenderer{
-		doc:  doc,
-		body: body,
+		Scheduler: render.Scheduler{Clock: AnimationFrames{}},
+		doc:       doc,
+		body:      &WebComponent{name: "body", el: &body},
 	}
 }
 
+// AnimationFrames is a render.Clock whose frames are
+// the browser's animation frames.
+type AnimationFrames struct{}
+
+func (AnimationFrames) RequestFrame(fn func()) {
+	var cb js.Func
+	cb = js.FuncOf(func(this js.Value, args []js.Value) any {
+		cb.Release()
+		fn()
+		return nil
+	})
+	js.Global().Call("requestAnimationFrame", cb)
+}
+
 func (r *DOMRenderer) NewComponent(name string) render.Component {
 	return &WebComponent{
 		name: name,
 	}
 }
 
+func (r *DOMRenderer) NewText(text string) render.Component {
+	return &WebComponent{
+		name:  render.TextName,
+		text:  text,
+		attrs: map[string]any{render.TextAttribute: text},
+	}
+}
+
 func (r *DOMRenderer) Render(comps ...render.Component) {
 	for _, c := range comps {
-		webC, ok := c.(*WebComponent)
-		if !ok {
-			panic("invalid comp type")
-		}
-
-		frag, _ := r.createElement(webC)
-		r.body.Call("appendChild", frag)
+		r.Insert(r.body, c, nil)
 	}
 }
 
 func (r *DOMRenderer) createElement(c *WebComponent) (frag, el js.Value) {
+	if c.name == render.TextName {
+		// Text nodes are inserted as they are
+		el = r.doc.Call("createTextNode", c.text)
+		c.el = &el
+		return el, el
+	}
+
 	frag = r.doc.Call("createDocumentFragment")
 
 	el = r.doc.Call("createElement", c.name)
 	c.el = &el
 
-	if c.text != "" {
-		el.Set("innerText", c.text)
-	}
-
 	for key, val := range c.attrs {
-		if strings.HasPrefix(key, "on:") {
-			valFunc, ok := val.(func())
-			if !ok {
-				log.Fatal("only func allowed for eventlisteners")
-			}
-
-			jsFunc := js.FuncOf(func(this js.Value, args []js.Value) any {
-				valFunc()
-				return nil
-			})
-			// todo: handle release
-			el.Call("addEventListener", strings.TrimPrefix(key, "on:"), jsFunc)
-
-			continue
-		}
-
-		if strings.HasPrefix(key, "bind:") {
-			valFunc, ok := val.(func(val any))
-			if !ok {
-				log.Fatal("expected func(val any) when binding")
-			}
-
-			attr := strings.TrimPrefix(key, "bind:")
-			switch attr {
-			case "value":
-				jsFunc := js.FuncOf(func(this js.Value, args []js.Value) any {
-					valFunc(el.Get("value").String())
-					return nil
-				})
-				// todo: handle release
-				el.Call("addEventListener", "input", jsFunc)
-			default:
-				panic("bind to " + attr + "not implemented")
-			}
-			continue
-		}
-
-		c.SetAttribute(key, val)
+		c.apply(key, val)
 	}
 
-	for _, cc := range c.comps {
+	for _, cc := range c.Children() {
 		webCC, ok := cc.(*WebComponent)
 		if !ok {
 			panic("invalid comp type")
@@ -148,26 +205,66 @@ func (r *DOMRenderer) createElement(c *WebComponent) (frag, el js.Value) {
 }
 
 func (r *DOMRenderer) Append(parent render.Component, child render.Component) {
-	p, ok := parent.(*WebComponent)
-	if !ok {
-		panic("invalid parent comp type")
+	r.Insert(parent, child, nil)
+}
+
+// Reconcile moves, inserts and removes DOM nodes to match children,
+// so elements kept for a key keep their focus and state.
+func (r *DOMRenderer) Reconcile(parent render.Component, children []render.Component) []render.Component {
+	return render.Reconcile(r, mustWebComponent(parent), children)
+}
+
+// Insert moves child, or inserts it, into the children of parent
+// before the child before, or at their end if before is nil.
+func (r *DOMRenderer) Insert(parent render.Component, child render.Component, before render.Component) {
+	p, c := mustWebComponent(parent), mustWebComponent(child)
+
+	// Moving a mounted component does not mount it again
+	wasConnected := c.el != nil && c.el.Get("isConnected").Bool()
+
+	render.Link(p, c, before)
+
+	// Children of a parent without an element are
+	// created along with it once it is rendered
+	if p.el == nil {
+		return
 	}
 
-	c, ok := child.(*WebComponent)
-	if !ok {
-		panic("invalid parent comp type")
+	var node js.Value
+	if c.el != nil {
+		node = *c.el
+	} else {
+		frag, el := r.createElement(c)
+		c.el = &el
+		node = frag
 	}
 
-	if p.el == nil {
-		panic("nil parent el")
+	ref := js.Null()
+	if before != nil {
+		if b := mustWebComponent(before); b.el != nil {
+			ref = *b.el
+		}
 	}
+	p.el.Call("insertBefore", node, ref)
 
+	if !wasConnected && p.el.Get("isConnected").Bool() {
+		r.Mount(c)
+	}
+}
+
+func (r *DOMRenderer) Remove(comp render.Component) {
+	c := mustWebComponent(comp)
+	r.Unmount(c)
+	render.Unlink(c)
 	if c.el != nil {
-		p.el.Call("appendChild", *c.el)
-		return
+		c.el.Call("remove")
 	}
+}
 
-	frag, el := r.createElement(c)
-	c.el = &el
-	p.el.Call("appendChild", frag)
+func mustWebComponent(c render.Component) *WebComponent {
+	wc, ok := c.(*WebComponent)
+	if !ok {
+		panic(fmt.Sprintf("invalid comp type %T", c))
+	}
+	return wc
 }
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tifye/flamingo/render"
+)
+
+// fakeDOM installs a document with the part of the DOM
+// used by DOMRenderer, for running the tests in Node.
+const fakeDOM = `(() => {
+	class Node {
+		constructor(name) {
+			this.nodeName = name;
+			this.childNodes = [];
+			this.parentNode = null;
+			this.attributes = {};
+			this.listeners = {};
+		}
+		get isConnected() {
+			let n = this;
+			while (n.parentNode) n = n.parentNode;
+			return n === globalThis.document.body;
+		}
+		appendChild(child) { return this.insertBefore(child, null); }
+		insertBefore(child, ref) {
+			const nodes = child.nodeName === "#fragment" ? [...child.childNodes] : [child];
+			for (const n of nodes) {
+				n.remove();
+				const i = ref ? this.childNodes.indexOf(ref) : this.childNodes.length;
+				this.childNodes.splice(i, 0, n);
+				n.parentNode = this;
+			}
+			return child;
+		}
+		remove() {
+			const p = this.parentNode;
+			if (p) {
+				p.childNodes.splice(p.childNodes.indexOf(this), 1);
+				this.parentNode = null;
+			}
+		}
+		setAttribute(key, val) { this.attributes[key] = String(val); }
+		addEventListener(event, fn) { (this.listeners[event] = this.listeners[event] || []).push(fn); }
+		removeEventListener(event, fn) { this.listeners[event] = (this.listeners[event] || []).filter(f => f !== fn); }
+		dispatch(event) { for (const fn of this.listeners[event] || []) fn(); }
+	}
+	globalThis.document = {
+		body: new Node("BODY"),
+		createElement: name => new Node(name),
+		createTextNode: text => Object.assign(new Node("#text"), { nodeValue: text }),
+		createDocumentFragment: () => new Node("#fragment"),
+	};
+})()`
+
+func TestReconcile(t *testing.T) {
+	js.Global().Call("eval", fakeDOM)
+	r := NewDOMRenderer()
+	var clicked []string
+	li := func(key, label string) render.Component {
+		c := r.NewComponent("li")
+		c.SetAttribute(render.KeyAttribute, key)
+		c.SetAttribute("class", label)
+		c.SetAttribute("on:click", func() { clicked = append(clicked, label) })
+		return c
+	}
+	ul := r.NewComponent("ul")
+	a, b := li("a", "a1"), li("b", "b1")
+	r.Append(ul, a)
+	r.Append(ul, b)
+	r.Render(ul)
+
+	result := r.Reconcile(ul, []render.Component{li("b", "b2"), li("a", "a2")})
+	require.Same(t, b, result[0])
+	require.Same(t, a, result[1])
+	el := a.(*WebComponent).Element()
+	assert.Equal(t, "a2", el.Get("attributes").Get("class").String())
+
+	el.Call("dispatch", "click")
+	assert.Equal(t, []string{"a2"}, clicked, "expected the listener of the kept element to be replaced")
+	assert.Equal(t, 1, el.Get("listeners").Get("click").Length())
+}